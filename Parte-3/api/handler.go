@@ -4,13 +4,23 @@ import (
 	"errors"
 	"net/http"
 	"parte3/internal/user"
+	"platform/etag"
+	"platform/logging"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // handler holds the user service and implements HTTP handlers for user CRUD.
 type handler struct {
 	userService *user.Service
+	logger      *zap.Logger
+}
+
+// log returns the logger of the request being served by c, tagged with
+// its request ID.
+func (h *handler) log(c *gin.Context) *zap.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
 }
 
 // handleCreate handles POST /users
@@ -32,6 +42,7 @@ func (h *handler) handleCreate(ctx *gin.Context) {
 		NickName: req.NickName,
 	}
 	if err := h.userService.Create(u); err != nil {
+		h.log(ctx).Error("create user failed", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -50,10 +61,12 @@ func (h *handler) handleRead(ctx *gin.Context) {
 			return
 		}
 
+		h.log(ctx).Error("read user failed", zap.String("id", id), zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.Header("ETag", etag.Format(u.Version))
 	ctx.JSON(http.StatusOK, u)
 }
 
@@ -62,23 +75,51 @@ func (h *handler) handleUpdate(ctx *gin.Context) {
 	id := ctx.Param("id")
 
 	// bind partial update fields
-	var fields *user.UpdateFields
+	var fields user.UpdateFields
 	if err := ctx.ShouldBindJSON(&fields); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	u, err := h.userService.Update(id, fields)
+	// the expected version may come in If-Match or in the body
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch != "" {
+		version, err := etag.ParseIfMatch(ifMatch)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if version != nil && fields.Version != nil && *fields.Version != *version {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "body version does not match If-Match"})
+			return
+		}
+		if version != nil {
+			fields.Version = version
+		}
+	}
+
+	u, err := h.userService.Update(id, &fields)
 	if err != nil {
 		if errors.Is(err, user.ErrNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, user.ErrVersionConflict) {
+			// If-Match failures are 412 per RFC 9110; a stale body version is 409
+			status := http.StatusConflict
+			if ifMatch != "" {
+				status = http.StatusPreconditionFailed
+			}
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 
+		h.log(ctx).Error("update user failed", zap.String("id", id), zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.Header("ETag", etag.Format(u.Version))
 	ctx.JSON(http.StatusOK, u)
 }
 
@@ -92,6 +133,7 @@ func (h *handler) handleDelete(ctx *gin.Context) {
 			return
 		}
 
+		h.log(ctx).Error("delete user failed", zap.String("id", id), zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
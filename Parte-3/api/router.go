@@ -3,26 +3,37 @@ package api
 import (
 	"net/http"
 	"parte3/internal/user"
+	"platform/health"
+	"platform/logging"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // InitRoutes registers all user CRUD endpoints on the given Gin engine.
-// It initializes the storage, service, and handler, then binds each HTTP
-// method and path to the appropriate handler function.
-func InitRoutes(e *gin.Engine) {
-	storage := user.NewLocalStorage()
+// It initializes the service and handler on top of the given storage, then
+// binds each HTTP method and path to the appropriate handler function.
+// GET /readyz reports the checks of checker, answering 503 if any fails.
+// Every request is identified by X-Request-ID and logged with logger, and
+// a panic is answered with 500.
+func InitRoutes(e *gin.Engine, storage user.Storage, checker *health.Checker, logger *zap.Logger) {
 	service := user.NewService(storage)
 
 	h := handler{
 		userService: service,
+		logger:      logger,
 	}
 
+	// recovery goes after the access log, so a panic is logged as a 500
+	e.Use(logging.Middleware(logger), gin.Recovery())
+
 	e.POST("/users", h.handleCreate)
 	e.GET("/users/:id", h.handleRead)
 	e.PATCH("/users/:id", h.handleUpdate)
 	e.DELETE("/users/:id", h.handleDelete)
 
+	e.GET("/healthz", health.Liveness)
+	e.GET("/readyz", health.Readiness(checker))
 	e.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "pong",
@@ -0,0 +1,123 @@
+// Package config loads the settings of the users service from an optional
+// YAML or TOML file and from environment variables, and validates them.
+package config
+
+import (
+	"net"
+	"time"
+
+	"platform/settings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrInvalidConfig is returned when a setting is unknown, malformed or
+// out of range.
+var ErrInvalidConfig = settings.ErrInvalid
+
+// EnvPrefix prefixes the environment variable of every setting: the key in
+// upper case with dots replaced by underscores, e.g. USERS_ADDR for addr.
+const EnvPrefix = "USERS_"
+
+// Config holds every setting of the users service.
+type Config struct {
+	// Addr is the address the HTTP server listens on (addr).
+	Addr string
+	// Mode is the Gin mode: debug, release or test (mode).
+	Mode     string
+	Log      LogConfig
+	Shutdown ShutdownConfig
+	Health   HealthConfig
+}
+
+// LogConfig configures the zap logger.
+type LogConfig struct {
+	Level  string // log.level: debug, info, warn or error
+	Format string // log.format: json or console
+}
+
+// ShutdownConfig bounds the graceful shutdown.
+type ShutdownConfig struct {
+	Timeout time.Duration // shutdown.timeout: drain and hooks
+	Delay   time.Duration // shutdown.delay: not-ready before draining
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	Timeout time.Duration // health.timeout, per check
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Addr: ":8080",
+		Mode: gin.DebugMode,
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Shutdown: ShutdownConfig{
+			Timeout: 15 * time.Second,
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+// Load returns the configuration, validated. Each setting is taken from,
+// in increasing order of precedence: Default, the file at path (if path is
+// not empty), its environment variable (see EnvVar) and overrides, which
+// maps keys such as "addr" to values, e.g. from flags.
+//
+// The file is YAML (.yaml, .yml) or TOML (.toml); sections nest keys, so
+// shutdown.timeout is the timeout key of the shutdown section.
+func Load(path string, overrides map[string]string) (*Config, error) {
+	cfg := Default()
+	if err := settings.Load(cfg.settings(), EnvPrefix, path, overrides); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// EnvVar returns the environment variable that sets key.
+func EnvVar(key string) string {
+	return settings.EnvVar(EnvPrefix, key)
+}
+
+// settings lists every setting by key, with the function that parses a
+// value into c.
+func (c *Config) settings() []settings.Setting {
+	return []settings.Setting{
+		{Key: "addr", Set: settings.String(&c.Addr)},
+		{Key: "mode", Set: settings.String(&c.Mode)},
+		{Key: "log.level", Set: settings.String(&c.Log.Level)},
+		{Key: "log.format", Set: settings.String(&c.Log.Format)},
+		{Key: "shutdown.timeout", Set: settings.Duration(&c.Shutdown.Timeout)},
+		{Key: "shutdown.delay", Set: settings.Duration(&c.Shutdown.Delay)},
+		{Key: "health.timeout", Set: settings.Duration(&c.Health.Timeout)},
+	}
+}
+
+// Validate checks every setting and reports all the problems found,
+// wrapping ErrInvalidConfig.
+func (c *Config) Validate() error {
+	var p settings.Problems
+
+	_, _, err := net.SplitHostPort(c.Addr)
+	p.Check(err == nil, "addr %q is not host:port", c.Addr)
+	p.Check(c.Mode == gin.DebugMode || c.Mode == gin.ReleaseMode || c.Mode == gin.TestMode,
+		"mode %q must be debug, release or test", c.Mode)
+	_, err = zapcore.ParseLevel(c.Log.Level)
+	p.Check(err == nil, "log.level %q is unknown", c.Log.Level)
+	p.Check(c.Log.Format == "json" || c.Log.Format == "console", "log.format %q must be json or console", c.Log.Format)
+	p.Check(c.Shutdown.Timeout > 0, "shutdown.timeout must be positive")
+	p.Check(c.Shutdown.Delay >= 0, "shutdown.delay cannot be negative")
+	p.Check(c.Health.Timeout > 0, "health.timeout must be positive")
+
+	return p.Err()
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "users.yaml")
+	if err := os.WriteFile(yamlPath, []byte("addr: \":9000\"\nmode: release\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tomlPath := filepath.Join(dir, "users.toml")
+	if err := os.WriteFile(tomlPath, []byte("mode = \"test\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cfg != Default() {
+		t.Fatalf("expected defaults, got %+v", cfg)
+	}
+
+	cfg, err = Load(tomlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mode != "test" || cfg.Addr != ":8080" {
+		t.Fatalf("unexpected TOML config %+v", cfg)
+	}
+
+	// the environment overrides the file, and overrides the environment
+	t.Setenv("USERS_MODE", "debug")
+	t.Setenv("USERS_ADDR", ":9001")
+	cfg, err = Load(yamlPath, map[string]string{"addr": ":9002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mode != "debug" || cfg.Addr != ":9002" {
+		t.Fatalf("unexpected precedence result %+v", cfg)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.yaml")
+	if err := os.WriteFile(path, []byte("port: 8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path, nil); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), `"port"`) {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	// every problem is reported at once
+	_, err := Load("", map[string]string{"addr": "8080", "mode": "prod"})
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "addr") || !strings.Contains(err.Error(), "mode") {
+		t.Fatalf("expected addr and mode errors, got %v", err)
+	}
+
+	_, err = Load("", map[string]string{"log.level": "loud", "log.format": "xml"})
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "log.level") || !strings.Contains(err.Error(), "log.format") {
+		t.Fatalf("expected log.level and log.format errors, got %v", err)
+	}
+}
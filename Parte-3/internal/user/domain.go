@@ -19,4 +19,7 @@ type UpdateFields struct {
 	Name     *string `json:"name"`
 	Address  *string `json:"address"`
 	NickName *string `json:"nickname"`
+	// Version, when set, is the version the client last read; the update is
+	// rejected with ErrVersionConflict if the user has changed since.
+	Version *int `json:"version"`
 }
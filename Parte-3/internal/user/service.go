@@ -6,14 +6,14 @@ import (
 	"github.com/google/uuid"
 )
 
-// Service provides high-level user management operations on a LocalStorage backend.
+// Service provides high-level user management operations on a Storage backend.
 type Service struct {
 	// storage is the underlying persistence for User entities.
-	storage *LocalStorage
+	storage Storage
 }
 
 // NewService creates a new Service.
-func NewService(storage *LocalStorage) *Service {
+func NewService(storage Storage) *Service {
 	return &Service{
 		storage: storage,
 	}
@@ -40,13 +40,18 @@ func (s *Service) Get(id string) (*User, error) {
 
 // Update modifies an existing user's data.
 // It updates Name, Address, NickName, sets UpdatedAt to now and increments Version.
-// Returns ErrNotFound if the user does not exist, or ErrEmptyID if user.ID is empty.
+// Returns ErrNotFound if the user does not exist, or ErrVersionConflict if
+// user.Version is set and stale or a concurrent update won the race.
 func (s *Service) Update(id string, user *UpdateFields) (*User, error) {
 	existing, err := s.storage.Read(id)
 	if err != nil {
 		return nil, err
 	}
 
+	if user.Version != nil && *user.Version != existing.Version {
+		return nil, ErrVersionConflict
+	}
+
 	if user.Name != nil {
 		existing.Name = *user.Name
 	}
@@ -59,10 +64,11 @@ func (s *Service) Update(id string, user *UpdateFields) (*User, error) {
 		existing.NickName = *user.NickName
 	}
 
+	version := existing.Version
 	existing.UpdatedAt = time.Now()
 	existing.Version++
 
-	if err := s.storage.Set(existing); err != nil {
+	if err := s.storage.CompareAndSet(existing, version); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_UpdateVersionConflict(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+	u := &User{Name: "Ana", Address: "calle"}
+	if err := svc.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Beto"
+	stale := 1
+	if _, err := svc.Update(u.ID, &UpdateFields{Name: &name, Version: &stale}); err != nil {
+		t.Fatalf("first update with current version failed: %v", err)
+	}
+
+	// the user is now at version 2, so replaying version 1 must fail
+	if _, err := svc.Update(u.ID, &UpdateFields{Name: &name, Version: &stale}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+}
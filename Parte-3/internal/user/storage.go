@@ -1,6 +1,10 @@
 package user
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"sync"
+)
 
 // ErrNotFound is returned when a user with the given ID is not found.
 var ErrNotFound = errors.New("user not found")
@@ -8,9 +12,34 @@ var ErrNotFound = errors.New("user not found")
 // ErrEmptyID is returned when trying to store a user with an empty ID.
 var ErrEmptyID = errors.New("empty user ID")
 
+// ErrVersionConflict is returned by a conditional write when the stored
+// user no longer has the expected version.
+var ErrVersionConflict = errors.New("user version conflict")
+
+// Storage is the persistence contract used by Service.
+// Implementations must return ErrNotFound for unknown IDs and ErrEmptyID
+// when asked to store a user without an ID.
+type Storage interface {
+	// Set stores or updates a user.
+	Set(user *User) error
+	// CompareAndSet replaces a stored user only if its current version
+	// equals version, returning ErrVersionConflict otherwise.
+	CompareAndSet(user *User, version int) error
+	// Read retrieves a user by ID.
+	Read(id string) (*User, error)
+	// Delete removes a user by ID.
+	Delete(id string) error
+	// Ping reports whether the storage can serve requests.
+	Ping(ctx context.Context) error
+}
+
 // LocalStorage provides an in-memory implementation for storing users.
+// It is safe for concurrent use: every method takes the internal lock and
+// values are copied on the way in and out, so callers never share memory
+// with the store.
 type LocalStorage struct {
-	m map[string]*User
+	mu sync.RWMutex
+	m  map[string]*User
 }
 
 // NewLocalStorage instantiates a new LocalStorage with an empty map.
@@ -27,29 +56,67 @@ func (l *LocalStorage) Set(user *User) error {
 		return ErrEmptyID
 	}
 
-	l.m[user.ID] = user
+	stored := *user
+	l.mu.Lock()
+	l.m[user.ID] = &stored
+	l.mu.Unlock()
+	return nil
+}
+
+// CompareAndSet replaces a user in the local storage only if the stored
+// version equals version.
+// Returns ErrNotFound if the user does not exist, or ErrVersionConflict if
+// it was modified in the meantime.
+func (l *LocalStorage) CompareAndSet(user *User, version int) error {
+	if user.ID == "" {
+		return ErrEmptyID
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.m[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Version != version {
+		return ErrVersionConflict
+	}
+
+	stored := *user
+	l.m[user.ID] = &stored
 	return nil
 }
 
 // Read retrieves a user from the local storage by ID.
 // Returns ErrNotFound if the user is not found.
 func (l *LocalStorage) Read(id string) (*User, error) {
+	l.mu.RLock()
 	u, ok := l.m[id]
+	l.mu.RUnlock()
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	return u, nil
+	found := *u
+	return &found, nil
 }
 
 // Delete removes a user from the local storage by ID.
 // Returns ErrNotFound if the user does not exist.
 func (l *LocalStorage) Delete(id string) error {
-	_, err := l.Read(id)
-	if err != nil {
-		return err
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.m[id]; !ok {
+		return ErrNotFound
 	}
 
 	delete(l.m, id)
 	return nil
 }
+
+// Ping always succeeds: the local storage lives in memory.
+func (l *LocalStorage) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestLocalStorage_ReturnsCopies(t *testing.T) {
+	s := NewLocalStorage()
+	in := &User{ID: "u1", Name: "Ana"}
+	if err := s.Set(in); err != nil {
+		t.Fatal(err)
+	}
+
+	in.Name = "Beto"
+	got, err := s.Read("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Ana" {
+		t.Fatalf("stored user changed through caller pointer: got %q", got.Name)
+	}
+
+	got.Name = "Carla"
+	again, _ := s.Read("u1")
+	if again.Name != "Ana" {
+		t.Fatalf("stored user changed through read pointer: got %q", again.Name)
+	}
+}
+
+// Correr con -race: crea, actualiza y borra usuarios en paralelo.
+func TestService_ConcurrentAccess(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+
+	const workers = 16
+	const perWorker = 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				u := &User{Name: fmt.Sprintf("user-%d-%d", w, i), Address: "calle"}
+				if err := svc.Create(u); err != nil {
+					t.Error(err)
+					return
+				}
+				nick := "nick"
+				if _, err := svc.Update(u.ID, &UpdateFields{NickName: &nick}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := svc.Get(u.ID); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%2 == 0 {
+					if err := svc.Delete(u.ID); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
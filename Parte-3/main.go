@@ -1,16 +1,78 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"os/signal"
 	"parte3/api"
+	"parte3/internal/config"
+	"parte3/internal/user"
+	"platform/health"
+	"platform/lifecycle"
+	"platform/logging"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func main() {
-	r := gin.Default()
-	api.InitRoutes(r)
+	configPath := flag.String("config", os.Getenv("USERS_CONFIG"), "YAML or TOML configuration file (default $USERS_CONFIG)")
+	addr := flag.String("addr", "", "address to listen on (default :8080)")
+	flag.Parse()
+
+	// an explicit flag overrides the file and the environment
+	overrides := map[string]string{}
+	if *addr != "" {
+		overrides["addr"] = *addr
+	}
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		panic(fmt.Errorf("error loading configuration: %v", err))
+	}
+
+	logger, err := logging.New(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		panic(fmt.Errorf("error initializing logger: %v", err))
+	}
+
+	gin.SetMode(cfg.Mode)
+	// without Gin's middleware: InitRoutes logs every request with zap and
+	// recovers panics
+	r := gin.New()
+	app := lifecycle.New(&http.Server{Addr: cfg.Addr, Handler: r},
+		lifecycle.WithShutdownTimeout(cfg.Shutdown.Timeout),
+		lifecycle.WithReadinessDelay(cfg.Shutdown.Delay),
+	)
+	storage := user.NewLocalStorage()
+
+	// what GET /readyz checks before reporting ready
+	// (/readyz only says which check failed; the error goes to the log)
+	checker := health.NewChecker(
+		health.WithTimeout(cfg.Health.Timeout),
+		health.WithErrorHandler(func(name string, err error) {
+			logger.Warn("health check failed", zap.String("check", name), zap.Error(err))
+		}),
+	)
+	checker.Register("lifecycle", health.ReadyCheck(app.Ready))
+	checker.Register("storage", storage.Ping)
+
+	api.InitRoutes(r, storage, checker, logger)
+
+	// flushed last, so it keeps what the rest of the shutdown logs
+	app.OnShutdown("logger", func(context.Context) error {
+		// Sync fails on some terminals (stderr cannot be synced); not a real error
+		_ = logger.Sync()
+		return nil
+	})
 
-	if err := r.Run(":8080"); err != nil {
-		panic(fmt.Errorf("error trying to start server: %v", err))
+	// SIGINT or SIGTERM start the graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Run(ctx); err != nil {
+		panic(fmt.Errorf("error running server: %v", err))
 	}
 }
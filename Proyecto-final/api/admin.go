@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sales-api/internal/usersclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminHeader carries the token that authorizes administrative endpoints.
+const adminHeader = "X-Admin-Token"
+
+// requireAdmin only lets through requests carrying token in adminHeader.
+// With an empty token administrative endpoints are disabled altogether.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "operación de administración deshabilitada"})
+			return
+		}
+
+		got := c.GetHeader(adminHeader)
+		if got == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "falta el header " + adminHeader})
+			return
+		}
+		// comparación en tiempo constante para no filtrar el token
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token de administración inválido"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// handleInvalidateUser handles DELETE /admin/users/:id/cache: drops the
+// cached lookup of a user, so a change in the users service is seen on the
+// next sale instead of when the entry expires. Routed behind requireAdmin.
+func handleInvalidateUser(cache *usersclient.CachedClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cache.Invalidate(c.Param("id"))
+		c.Status(http.StatusNoContent)
+	}
+}
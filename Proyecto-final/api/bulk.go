@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sales-api/internal/sale"
+	"unicode"
+)
+
+// maxBulkRows limits how many sales a single POST /sales/bulk may carry.
+const maxBulkRows = 1000
+
+// Row outcomes reported by POST /sales/bulk.
+const (
+	bulkCreated = "created"
+	bulkFailed  = "failed"
+	// bulkSkipped marks valid rows that were not stored because another
+	// row failed in an all-or-nothing import.
+	bulkSkipped = "skipped"
+)
+
+// bulkRow is one sale of a bulk import: a create request that may also
+// carry the state the sale starts in.
+type bulkRow struct {
+	createRequest
+	Estado string `json:"estado"`
+
+	// err is set when the row is valid JSON but does not fit the schema.
+	err error
+}
+
+// bulkResult is the outcome of one row, numbered from 1 in input order.
+type bulkResult struct {
+	Row    int        `json:"row"`
+	Status string     `json:"status"`
+	Sale   *sale.Sale `json:"sale,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// bulkReport is the response of POST /sales/bulk.
+type bulkReport struct {
+	Atomic  bool         `json:"atomic"`
+	Created int          `json:"created"`
+	Failed  int          `json:"failed"`
+	Results []bulkResult `json:"results"`
+}
+
+func newBulkReport(rows int, atomic bool) *bulkReport {
+	r := &bulkReport{Atomic: atomic, Results: make([]bulkResult, rows)}
+	for i := range r.Results {
+		r.Results[i].Row = i + 1
+	}
+	return r
+}
+
+func (r *bulkReport) fail(i int, err error) {
+	r.Results[i].Status = bulkFailed
+	r.Results[i].Error = err.Error()
+	r.Failed++
+}
+
+func (r *bulkReport) create(i int, s *sale.Sale) {
+	r.Results[i].Status = bulkCreated
+	r.Results[i].Sale = s
+	r.Created++
+}
+
+// decodeBulkRows reads the rows of a bulk import, sent either as a JSON
+// array or as NDJSON (one object per line). Rows whose fields have the
+// wrong type are returned with their error so they are reported on their
+// own; malformed JSON fails the whole request.
+func decodeBulkRows(r io.Reader) ([]bulkRow, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return nil, errors.New("no hay ventas para importar")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	isArray := first == '['
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []bulkRow
+	for {
+		if isArray && !dec.More() {
+			break
+		}
+		var row bulkRow
+		err := dec.Decode(&row)
+		if err == io.EOF && !isArray {
+			break
+		}
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("fila %d: %w", len(rows)+1, err)
+			}
+			row.err = err
+		}
+		if len(rows) == maxBulkRows {
+			return nil, fmt.Errorf("se admiten hasta %d ventas por importación", maxBulkRows)
+		}
+		rows = append(rows, row)
+	}
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no hay ventas para importar")
+	}
+	return rows, nil
+}
+
+// firstNonSpace returns the first non-whitespace byte of br without
+// consuming it.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
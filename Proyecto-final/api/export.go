@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sales-api/internal/sale"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats accepted by GET /sales/export.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// exportFlushEvery is how many rows are buffered before flushing them to
+// the client.
+const exportFlushEvery = 100
+
+// csvHeader lists the columns of the CSV export, in order. deleted_at is
+// empty unless the sale was soft-deleted (see include_deleted).
+var csvHeader = []string{"id", "user_id", "estado", "amount", "amount_minor", "currency", "created_at", "updated_at", "version", "deleted_at"}
+
+// saleWriter encodes sales one at a time in an export format.
+type saleWriter interface {
+	Write(s sale.Sale) error
+	// Flush pushes buffered rows to the underlying writer.
+	Flush() error
+}
+
+// newSaleWriter returns the writer for format and its content type, or
+// ok == false if the format is unknown.
+func newSaleWriter(format string, w io.Writer) (sw saleWriter, contentType string, ok bool) {
+	switch format {
+	case formatCSV:
+		return &csvSaleWriter{w: csv.NewWriter(w)}, "text/csv; charset=utf-8", true
+	case formatNDJSON:
+		return &ndjsonSaleWriter{enc: json.NewEncoder(w)}, "application/x-ndjson", true
+	default:
+		return nil, "", false
+	}
+}
+
+// csvSaleWriter writes a header row followed by one row per sale.
+type csvSaleWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (cw *csvSaleWriter) Write(s sale.Sale) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	var deletedAt string
+	if s.DeletedAt != nil {
+		deletedAt = s.DeletedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cw.w.Write([]string{
+		csvText(s.ID),
+		csvText(s.UserID),
+		csvText(s.Estado),
+		s.Amount.String(),
+		strconv.FormatInt(s.Amount.Minor, 10),
+		csvText(s.Amount.Currency),
+		s.CreatedAt.UTC().Format(time.RFC3339Nano),
+		s.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		strconv.Itoa(s.Version),
+		deletedAt,
+	})
+}
+
+// Flush also writes the header, so an empty export is still a valid CSV.
+func (cw *csvSaleWriter) Flush() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// csvText neutralizes a text cell that a spreadsheet would evaluate as a
+// formula (CSV injection), prefixing it with a quote. Text cells such as
+// user_id come from clients.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (cw *csvSaleWriter) writeHeader() error {
+	if cw.headerWritten {
+		return nil
+	}
+	cw.headerWritten = true
+	return cw.w.Write(csvHeader)
+}
+
+// ndjsonSaleWriter writes one JSON object per line, in the same shape as
+// the other endpoints return sales.
+type ndjsonSaleWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonSaleWriter) Write(s sale.Sale) error {
+	return nw.enc.Encode(s)
+}
+
+func (nw *ndjsonSaleWriter) Flush() error {
+	return nil
+}
+
+// flushResponse sends what has been written so far to the client, if the
+// response writer supports it.
+func flushResponse(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1,62 +1,66 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"platform/etag"
+	"platform/logging"
 	"sales-api/internal/sale"
+	"sales-api/internal/stream"
+	"sales-api/internal/usersclient"
+	"sales-api/internal/webhook"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
-// Define una interfaz para poder inyectar mock en testing
-type HTTPClient interface {
-	Get(url string) (*resty.Response, error)
-}
-
-// realHTTPClient implementa HTTPClient usando resty.Client
-type realHTTPClient struct {
-	client *resty.Client
-}
-
-func (r *realHTTPClient) Get(url string) (*resty.Response, error) {
-	return r.client.R().Get(url)
-}
+// actorHeader identifies who performs a change, for the sale history.
+const actorHeader = "X-Actor"
 
 // handler holds the sale service and implements HTTP handlers for sale CRUD.
 type handler struct {
 	saleService *sale.Service
-	httpClient  HTTPClient
+	users       usersclient.Client
 	logger      *zap.Logger
+	webhooks    *webhook.Service
+	broker      *stream.Broker
+	heartbeat   time.Duration
+}
+
+// log returns the logger of the request being served by c, tagged with
+// its request ID.
+func (h *handler) log(c *gin.Context) *zap.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
+// createRequest is the payload of POST /sales.
+type createRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	// el monto puede venir como decimal (amount) o en centavos (amount_minor)
+	Amount      json.Number `json:"amount"`
+	AmountMinor *int64      `json:"amount_minor"`
+	// código ISO 4217; sin moneda se asume sale.DefaultCurrency
+	Currency string `json:"currency"`
 }
 
 // handleCreate handles POST /sales
 func (h *handler) handleCreate(ctx *gin.Context) {
 	// request payload
-	var req struct {
-		UserID string  `json:"user_id" binding:"required"`
-		Amount float32 `json:"amount" binding:"required,gt=0"`
-	}
+	var req createRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Validar que el usuario exista
-	resp, err := h.httpClient.Get("http://localhost:8080/users/" + req.UserID)
+	u, status, err := h.newSale(ctx, req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error al contactar servicio de usuarios"})
+		ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
-	if resp.StatusCode() != http.StatusOK {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "el usuario no existe"})
-		return
-	}
-	u := &sale.Sale{
-		UserID: req.UserID,
-		Amount: req.Amount,
-	}
 	if err := h.saleService.Create(u); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -65,24 +69,181 @@ func (h *handler) handleCreate(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, u)
 }
 
-// handleRead handles GET /sales/:id
-func (h *handler) handleRead(ctx *gin.Context) {
-	/*
-		id := ctx.Param("id")
+// handleBulkCreate handles POST /sales/bulk: imports a JSON array or an
+// NDJSON stream of sales, validating each row like POST /sales plus its
+// optional starting estado, and answers with a per-row report.
+// With atomic=true either every row is stored or none is.
+func (h *handler) handleBulkCreate(c *gin.Context) {
+	atomic, err := queryBool(c, "atomic")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		u, err := h.saleService.Get(id)
+	rows, err := decodeBulkRows(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := newBulkReport(len(rows), atomic)
+	sales := make([]*sale.Sale, len(rows))
+	for i, row := range rows {
+		if row.err == nil && row.UserID == "" {
+			row.err = errors.New("user_id es obligatorio")
+		}
+		if row.err != nil {
+			report.fail(i, row.err)
+			continue
+		}
+
+		s, _, err := h.newSale(c, row.createRequest)
 		if err != nil {
-			if errors.Is(err, sale.ErrNotFound) {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-				return
+			report.fail(i, err)
+			continue
+		}
+		s.Estado = row.Estado
+		if err := h.saleService.ValidateImport(*s); err != nil {
+			report.fail(i, err)
+			continue
+		}
+		sales[i] = s
+	}
+
+	if atomic {
+		h.createAllOrNothing(c, report, sales)
+		return
+	}
+
+	for i, s := range sales {
+		if s == nil {
+			continue
+		}
+		if err := h.saleService.Import(s); err != nil {
+			report.fail(i, err)
+			continue
+		}
+		report.create(i, s)
+	}
+
+	status := http.StatusCreated
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, report)
+}
+
+// createAllOrNothing stores the validated sales of a bulk import in one
+// atomic write, or none of them if any row failed validation.
+func (h *handler) createAllOrNothing(c *gin.Context, report *bulkReport, sales []*sale.Sale) {
+	if report.Failed > 0 {
+		for i, s := range sales {
+			if s != nil {
+				report.Results[i].Status = bulkSkipped
 			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
 
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.saleService.ImportAll(sales); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i, s := range sales {
+		report.create(i, s)
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// newSale validates a create request, including that the user exists,
+// and builds the sale to create. On error it also returns the HTTP status
+// that describes it.
+func (h *handler) newSale(ctx *gin.Context, req createRequest) (*sale.Sale, int, error) {
+	currency := sale.DefaultCurrency
+	if req.Currency != "" {
+		currency = sale.NormalizeCurrency(req.Currency)
+	}
+	if !sale.IsCurrency(currency) {
+		return nil, http.StatusBadRequest, fmt.Errorf("%w: %q", sale.ErrInvalidCurrency, req.Currency)
+	}
+	amount, err := requestAmount(req.Amount, req.AmountMinor, currency)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	// Validar que el usuario exista
+	if _, err := h.users.GetUser(ctx.Request.Context(), req.UserID); err != nil {
+		if errors.Is(err, usersclient.ErrNotFound) {
+			return nil, http.StatusBadRequest, errors.New("el usuario no existe")
+		}
+		h.log(ctx).Warn("user lookup failed", zap.String("user_id", req.UserID), zap.Error(err))
+		if errors.Is(err, usersclient.ErrRejected) {
+			return nil, http.StatusBadGateway, errors.New("el servicio de usuarios rechazó la consulta")
+		}
+		return nil, http.StatusServiceUnavailable, errors.New("error al contactar servicio de usuarios")
+	}
+
+	return &sale.Sale{
+		UserID: req.UserID,
+		Amount: amount,
+	}, 0, nil
+}
+
+// requestAmount builds the amount of a request from either its decimal
+// or its minor-units form; exactly one must be present and positive.
+func requestAmount(decimal json.Number, minor *int64, currency string) (sale.Money, error) {
+	var (
+		amount sale.Money
+		err    error
+	)
+	switch {
+	case decimal != "" && minor != nil:
+		return amount, errors.New("usar amount o amount_minor, no ambos")
+	case minor != nil:
+		amount = sale.NewMoney(*minor, currency)
+	case decimal != "":
+		if amount, err = sale.ParseMoney(decimal.String(), currency); err != nil {
+			return amount, err
+		}
+	default:
+		return amount, errors.New("amount es requerido")
+	}
+
+	if !amount.IsPositive() {
+		return amount, fmt.Errorf("%w: debe ser mayor a cero", sale.ErrInvalidAmount)
+	}
+	return amount, nil
+}
+
+// handleRead handles GET /sales/:id
+// Soft-deleted sales are only returned with include_deleted=true.
+func (h *handler) handleRead(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	includeDeleted, err := queryBool(ctx, "include_deleted")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u *sale.Sale
+	if includeDeleted {
+		u, err = h.saleService.GetWithDeleted(id)
+	} else {
+		u, err = h.saleService.Get(id)
+	}
+	if err != nil {
+		if errors.Is(err, sale.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, u)
-	*/
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("ETag", etag.Format(u.Version))
+	ctx.JSON(http.StatusOK, u)
 }
 
 // handleUpdate handles PUT /sales/:id
@@ -90,17 +251,36 @@ func (h *handler) handleUpdate(ctx *gin.Context) {
 	id := ctx.Param("id")
 
 	// bind partial update fields
-	var fields *sale.UpdateFields
+	var fields sale.UpdateFields
 	if err := ctx.ShouldBindJSON(&fields); err != nil {
-		h.logger.Warn("binding error", zap.Error(err))
+		h.log(ctx).Warn("binding error", zap.Error(err))
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	u, err := h.saleService.Update(id, fields)
+	// la versión esperada puede venir en If-Match o en el body
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch != "" {
+		version, err := etag.ParseIfMatch(ifMatch)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if version != nil && fields.Version != nil && *fields.Version != *version {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "la versión del body no coincide con If-Match"})
+			return
+		}
+		if version != nil {
+			fields.Version = version
+		}
+	}
+
+	fields.Actor = requestActor(ctx)
+
+	u, err := h.saleService.Update(id, &fields)
 
 	if err != nil {
-		h.logger.Warn("update failed", zap.String("id", id), zap.Error(err))
+		h.log(ctx).Warn("update failed", zap.String("id", id), zap.Error(err))
 		if errors.Is(err, sale.ErrSaleNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -113,19 +293,96 @@ func (h *handler) handleUpdate(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, sale.ErrTransitionRejected) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sale.ErrVersionConflict) {
+			// con If-Match la semántica HTTP es 412; con version en el body, 409
+			status := http.StatusConflict
+			if ifMatch != "" {
+				status = http.StatusPreconditionFailed
+			}
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.Header("ETag", etag.Format(u.Version))
 	ctx.JSON(http.StatusOK, u)
 }
 
+// handleHistory handles GET /sales/:id/history
+func (h *handler) handleHistory(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	history, err := h.saleService.History(id)
+	if err != nil {
+		if errors.Is(err, sale.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sale_id": id,
+		"results": history,
+	})
+}
+
 // handleDelete handles DELETE /sales/:id
+// The sale is soft-deleted: it disappears from reads and listings but is
+// kept for audit. An If-Match header makes the deletion conditional.
 func (h *handler) handleDelete(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	if err := h.saleService.Delete(id); err != nil {
+	var version *int
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" {
+		var err error
+		if version, err = etag.ParseIfMatch(ifMatch); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.saleService.Delete(id, version, requestActor(ctx)); err != nil {
+		switch {
+		case errors.Is(err, sale.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, sale.ErrVersionConflict) && version != nil:
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case errors.Is(err, sale.ErrVersionConflict):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// requestActor returns who performs a change, from actorHeader, or
+// "anonymous" if the header is missing.
+func requestActor(c *gin.Context) string {
+	if actor := c.GetHeader(actorHeader); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+// handlePurge handles DELETE /admin/sales/:id: removes a sale and its
+// history for good. Routed behind requireAdmin.
+func (h *handler) handlePurge(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	if err := h.saleService.Purge(id); err != nil {
 		if errors.Is(err, sale.ErrNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -135,44 +392,305 @@ func (h *handler) handleDelete(ctx *gin.Context) {
 		return
 	}
 
+	h.log(ctx).Info("sale purged", zap.String("sale_id", id))
 	ctx.Status(http.StatusNoContent)
 }
 
 // Crear endpoint GET /sales con filtros por user_id y status.
+// Admite además rangos de fecha y monto (ver parseFilter), paginación con
+// limit y offset o cursor, y orden con sort y order.
+// The metadata describes every sale matching the filters, not just the
+// page; metadata.page has the quantity and totals of the page alone.
+// metadata.total_amount is always present: it is null when the sales are
+// in more than one currency, which metadata.totals then breaks down.
 func (h *handler) handleList(c *gin.Context) {
-	userID := c.Query("user_id")
-	status := c.Query("status")
-	validStates := map[string]bool{"approved": true, "rejected": true, "pending": true}
-	// no se pide esta validación, pero la coloco ya que no puede venir el id del user vacio!
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id es requerido"})
+	filter, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if status != "" && !validStates[status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "estado inválido"})
+
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	sales, err := h.saleService.ListByUserAndStatus(userID, status)
+	result, err := h.saleService.List(filter, page)
 	if err != nil {
+		if errors.Is(err, sale.ErrInvalidPage) || errors.Is(err, sale.ErrInvalidFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sale.ErrAmountOverflow) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	sales := result.Sales
+	stats := result.Stats
+	pageTotals, err := sale.Totals(sales)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata := gin.H{
+		"quantity": stats.Total.Count,
+		"page": gin.H{
+			"quantity": len(sales),
+			"totals":   pageTotals,
+		},
+	}
+	// un contador por cada estado declarado en la máquina de estados
+	for _, st := range h.saleService.StateMachine().States() {
+		metadata[st] = 0
+		if g := stats.Group(st); g != nil {
+			metadata[st] = g.Count
+		}
+	}
 
-	metadata := map[string]interface{}{
-		"quantity":     len(sales),
-		"approved":     0,
-		"rejected":     0,
-		"pending":      0,
-		"total_amount": 0.0,
+	totals := stats.Total.Sums()
+	metadata["totals"] = totals
+	// total_amount solo tiene sentido si todas las ventas son de una moneda;
+	// con varias queda en null y el detalle está en totals
+	metadata["total_amount"] = nil
+	switch len(totals) {
+	case 0:
+		metadata["total_amount"] = sale.NewMoney(0, sale.DefaultCurrency)
+	case 1:
+		for _, t := range totals {
+			metadata["total_amount"] = t
+		}
 	}
-	for _, s := range sales {
-		metadata[s.Estado] = metadata[s.Estado].(int) + 1
-		metadata["total_amount"] = metadata["total_amount"].(float64) + float64(s.Amount)
+
+	if to := c.Query("convert_to"); to != "" {
+		converted, err := h.saleService.ConvertTotals(totals, sale.NormalizeCurrency(to))
+		if err != nil {
+			c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		metadata["converted_total"] = gin.H{"amount": converted, "currency": converted.Currency}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"metadata": metadata,
-		"results":  sales,
+		"metadata":    metadata,
+		"results":     sales,
+		"total":       result.Total,
+		"next_cursor": result.NextCursor,
 	})
 }
+
+// handleStats handles GET /sales/stats: aggregates the sales matching the
+// same filters as GET /sales, optionally grouped with group_by
+// (user, status, day, week or month).
+func (h *handler) handleStats(c *gin.Context) {
+	filter, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.saleService.Stats(filter, c.Query("group_by"))
+	if err != nil {
+		if errors.Is(err, sale.ErrInvalidFilter) || errors.Is(err, sale.ErrInvalidGroupBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sale.ErrAmountOverflow) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleExport handles GET /sales/export?format=csv|ndjson: streams every
+// sale matching the same filters as GET /sales, flushing rows to the
+// client as they are written. The default format is csv.
+func (h *handler) handleExport(c *gin.Context) {
+	filter, err := h.parseFilter(c)
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", formatCSV)
+	w, contentType, ok := newSaleWriter(format, c.Writer)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("formato desconocido %q: usar csv o ndjson", format)})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sales.%s"`, format))
+	c.Status(http.StatusOK)
+
+	rows := 0
+	err = h.saleService.Export(filter, func(s sale.Sale) error {
+		if err := w.Write(s); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			flushResponse(c.Writer)
+		}
+		return nil
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// el status ya se envió: solo queda cortar la respuesta
+		h.log(c).Warn("sales export aborted", zap.String("format", format), zap.Int("rows", rows), zap.Error(err))
+		c.Abort()
+		return
+	}
+	flushResponse(c.Writer)
+}
+
+// conversionStatus maps the errors of sale.Service.ConvertTotals to HTTP.
+func conversionStatus(err error) int {
+	switch {
+	case errors.Is(err, sale.ErrInvalidCurrency):
+		return http.StatusBadRequest
+	case errors.Is(err, sale.ErrRateNotFound), errors.Is(err, sale.ErrAmountOverflow):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, sale.ErrNoRateProvider):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseFilter reads the listing filters from the query string:
+// user_id, status, currency, created_from, created_to (RFC 3339 or
+// YYYY-MM-DD, both inclusive), min_amount, max_amount, read in the
+// currency filter's minor unit, and include_deleted. All of them are
+// optional.
+func (h *handler) parseFilter(c *gin.Context) (sale.Filter, error) {
+	filter := sale.Filter{
+		UserID:   c.Query("user_id"),
+		Status:   c.Query("status"),
+		Currency: sale.NormalizeCurrency(c.Query("currency")),
+	}
+	if filter.Status != "" && !h.saleService.StateMachine().IsValid(filter.Status) {
+		return filter, errors.New("estado inválido")
+	}
+	if filter.Currency != "" && !sale.IsCurrency(filter.Currency) {
+		return filter, sale.ErrInvalidCurrency
+	}
+	var err error
+	if filter.IncludeDeleted, err = queryBool(c, "include_deleted"); err != nil {
+		return filter, err
+	}
+	amountCurrency := filter.Currency
+	if amountCurrency == "" {
+		amountCurrency = sale.DefaultCurrency
+	}
+
+	if v := c.Query("created_from"); v != "" {
+		if filter.CreatedFrom, err = parseDate(v, false); err != nil {
+			return filter, errors.New("created_from debe ser una fecha RFC 3339 o YYYY-MM-DD")
+		}
+	}
+	if v := c.Query("created_to"); v != "" {
+		if filter.CreatedTo, err = parseDate(v, true); err != nil {
+			return filter, errors.New("created_to debe ser una fecha RFC 3339 o YYYY-MM-DD")
+		}
+	}
+	if v := c.Query("min_amount"); v != "" {
+		if filter.MinAmount, err = parseAmount(v, amountCurrency); err != nil {
+			return filter, errors.New("min_amount debe ser un número")
+		}
+	}
+	if v := c.Query("max_amount"); v != "" {
+		if filter.MaxAmount, err = parseAmount(v, amountCurrency); err != nil {
+			return filter, errors.New("max_amount debe ser un número")
+		}
+	}
+
+	return filter, nil
+}
+
+// queryBool reads an optional boolean query parameter; absent means false.
+func queryBool(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s debe ser true o false", name)
+	}
+	return b, nil
+}
+
+// parseDate parses an RFC 3339 timestamp or a plain YYYY-MM-DD date (UTC).
+// With endOfDay, a plain date means its last instant, so that ranges
+// ending on that day include it.
+func parseDate(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func parseAmount(v, currency string) (*sale.Money, error) {
+	amount, err := sale.ParseMoney(v, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// parsePage reads the limit, offset, cursor, sort and order query parameters.
+func parsePage(c *gin.Context) (sale.Page, error) {
+	page := sale.Page{
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		return page, errors.New("order debe ser asc o desc")
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil {
+			return page, errors.New("limit debe ser un número")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil {
+			return page, errors.New("offset debe ser un número")
+		}
+	}
+
+	return page, nil
+}
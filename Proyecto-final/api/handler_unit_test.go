@@ -1,57 +1,57 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"platform/health"
+	"platform/requestid"
+	"sales-api/internal/config"
+	"sales-api/internal/metrics"
 	"sales-api/internal/sale"
+	"sales-api/internal/stream"
+	"sales-api/internal/usersclient"
+	"sales-api/internal/webhook"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
-// Fake client: usuario NO existente
-type fakeClientNotFound struct{}
-
-func (f *fakeClientNotFound) Get(url string) (*resty.Response, error) {
-	return &resty.Response{
-		RawResponse: &http.Response{
-			StatusCode: http.StatusNotFound,
-			Body:       http.NoBody,
-		},
-	}, nil
-}
-
-// Fake client: usuario existente
-type fakeClientOK struct{}
-
-func (f *fakeClientOK) Get(url string) (*resty.Response, error) {
-	return &resty.Response{
-		RawResponse: &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       http.NoBody,
-		},
-	}, nil
-}
+// usuario conocido por el fake del servicio de usuarios
+var knownUser = usersclient.User{ID: "abc123", Name: "nico"}
 
 // helper para crear handler
-func newHandler(client HTTPClient, logger *zap.Logger) handler {
+func newHandler(users usersclient.Client, logger *zap.Logger) handler {
 	return handler{
 		saleService: sale.NewService(sale.NewLocalStorage()),
-		httpClient:  client,
+		users:       users,
 		logger:      logger,
 	}
 }
 
-// createTestSale crea una venta con estado inicial forzado (por ej: "pending")
-func createTestSale(svc *sale.Service, userID string, amount float32, estado string) *sale.Sale {
+// ars construye un monto en pesos a partir de unidades enteras
+func ars(units int64) sale.Money {
+	return sale.NewMoney(units*100, sale.DefaultCurrency)
+}
+
+// createTestSale importa una venta con estado inicial forzado (por ej: "pending")
+func createTestSale(svc *sale.Service, userID string, amount sale.Money, estado string) *sale.Sale {
 	s := &sale.Sale{
 		UserID:    userID,
 		Amount:    amount,
@@ -60,7 +60,7 @@ func createTestSale(svc *sale.Service, userID string, amount float32, estado str
 		UpdatedAt: time.Now(),
 		Version:   1,
 	}
-	_ = svc.Create(s)
+	_ = svc.Import(s)
 	return s
 }
 
@@ -71,7 +71,7 @@ func TestCreateSale(t *testing.T) {
 
 	t.Run("Crear Venta: usuario inválido @400", func(t *testing.T) {
 		router := gin.New()
-		h := newHandler(&fakeClientNotFound{}, logger)
+		h := newHandler(usersclient.NewFake(), logger)
 		router.POST("/sales", h.handleCreate)
 
 		body := `{"user_id": "no-existe", "amount": 100}`
@@ -84,9 +84,43 @@ func TestCreateSale(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), "el usuario no existe")
 	})
 
+	t.Run("Crear Venta: servicio de usuarios caído @503", func(t *testing.T) {
+		router := gin.New()
+		users := usersclient.NewFake(knownUser)
+		users.Err = usersclient.ErrUnavailable
+		h := newHandler(users, logger)
+		router.POST("/sales", h.handleCreate)
+
+		body := `{"user_id": "abc123", "amount": 100}`
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "error al contactar servicio de usuarios")
+	})
+
+	t.Run("Crear Venta: servicio de usuarios rechaza la consulta @502", func(t *testing.T) {
+		router := gin.New()
+		users := usersclient.NewFake(knownUser)
+		users.Err = fmt.Errorf("%w: respuesta 401", usersclient.ErrRejected)
+		h := newHandler(users, logger)
+		router.POST("/sales", h.handleCreate)
+
+		body := `{"user_id": "abc123", "amount": 100}`
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Contains(t, rec.Body.String(), "el servicio de usuarios rechazó la consulta")
+	})
+
 	t.Run("Crear Venta: usuario válido @201", func(t *testing.T) {
 		router := gin.New()
-		h := newHandler(&fakeClientOK{}, logger)
+		h := newHandler(usersclient.NewFake(knownUser), logger)
 		router.POST("/sales", h.handleCreate)
 
 		body := `{"user_id": "abc123", "amount": 200}`
@@ -101,6 +135,61 @@ func TestCreateSale(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), `"estado"`)
 		assert.Contains(t, rec.Body.String(), `"id"`)
 	})
+
+	t.Run("Crear Venta: monto en centavos @201", func(t *testing.T) {
+		router := gin.New()
+		h := newHandler(usersclient.NewFake(knownUser), logger)
+		router.POST("/sales", h.handleCreate)
+
+		body := `{"user_id": "abc123", "amount_minor": 19999}`
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"amount":199.99`)
+		assert.Contains(t, rec.Body.String(), `"amount_minor":19999`)
+		assert.Contains(t, rec.Body.String(), `"currency":"ARS"`)
+	})
+
+	t.Run("Crear Venta: en otra moneda @201", func(t *testing.T) {
+		router := gin.New()
+		h := newHandler(usersclient.NewFake(knownUser), logger)
+		router.POST("/sales", h.handleCreate)
+
+		body := `{"user_id": "abc123", "amount": 1500, "currency": "jpy"}`
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"amount_minor":1500`)
+		assert.Contains(t, rec.Body.String(), `"currency":"JPY"`)
+	})
+
+	t.Run("Crear Venta: monto inválido @400", func(t *testing.T) {
+		router := gin.New()
+		h := newHandler(usersclient.NewFake(knownUser), logger)
+		router.POST("/sales", h.handleCreate)
+
+		for _, body := range []string{
+			`{"user_id": "abc123", "amount": 10.001}`,
+			`{"user_id": "abc123", "amount": 0}`,
+			`{"user_id": "abc123"}`,
+			`{"user_id": "abc123", "amount": 1, "amount_minor": 100}`,
+			`{"user_id": "abc123", "amount": 1, "currency": "PESOS"}`,
+			`{"user_id": "abc123", "amount": 1.5, "currency": "JPY"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
 }
 
 //======================= CREATE =======================//
@@ -116,11 +205,11 @@ func TestUpdateSale(t *testing.T) {
 		storage := sale.NewLocalStorage()
 		service := sale.NewService(storage)
 
-		s := createTestSale(service, "abc123", 150, "pending")
+		s := createTestSale(service, "abc123", ars(150), "pending")
 
 		h := handler{
 			saleService: service,
-			httpClient:  &fakeClientOK{},
+			users:       usersclient.NewFake(knownUser),
 			logger:      logger,
 		}
 		router.PATCH("/sales/:id", h.handleUpdate)
@@ -140,11 +229,11 @@ func TestUpdateSale(t *testing.T) {
 		storage := sale.NewLocalStorage()
 		service := sale.NewService(storage)
 
-		s := createTestSale(service, "abc123", 150, "pending")
+		s := createTestSale(service, "abc123", ars(150), "pending")
 
 		h := handler{
 			saleService: service,
-			httpClient:  &fakeClientOK{},
+			users:       usersclient.NewFake(knownUser),
 			logger:      logger,
 		}
 		router.PATCH("/sales/:id", h.handleUpdate)
@@ -161,7 +250,7 @@ func TestUpdateSale(t *testing.T) {
 
 	t.Run("error por id inexistente", func(t *testing.T) {
 		router := gin.New()
-		h := newHandler(&fakeClientOK{}, logger)
+		h := newHandler(usersclient.NewFake(knownUser), logger)
 		router.PATCH("/sales/:id", h.handleUpdate)
 
 		updateBody := `{"estado": "approved"}`
@@ -173,6 +262,26 @@ func TestUpdateSale(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, recUpdate.Code)
 		assert.Contains(t, recUpdate.Body.String(), "sale not found")
 	})
+
+	t.Run("body null no rompe el handler", func(t *testing.T) {
+		router := gin.New()
+		h := newHandler(usersclient.NewFake(knownUser), logger)
+		s := createTestSale(h.saleService, "abc123", ars(150), "pending")
+		router.PATCH("/sales/:id", h.handleUpdate)
+
+		for _, ifMatch := range []string{"", `"1"`} {
+			reqUpdate := httptest.NewRequest(http.MethodPatch, "/sales/"+s.ID, bytes.NewBufferString("null"))
+			reqUpdate.Header.Set("Content-Type", "application/json")
+			if ifMatch != "" {
+				reqUpdate.Header.Set("If-Match", ifMatch)
+			}
+			recUpdate := httptest.NewRecorder()
+			require.NotPanics(t, func() { router.ServeHTTP(recUpdate, reqUpdate) })
+
+			// sin estado es un cambio inválido, no un 500
+			assert.Equal(t, http.StatusBadRequest, recUpdate.Code, ifMatch)
+		}
+	})
 }
 
 //======================= UPDATE =======================//
@@ -189,12 +298,13 @@ func TestIntegration_FlujoCompleto(t *testing.T) {
 
 	h := handler{
 		saleService: service,
-		httpClient:  &fakeClientOK{},
+		users:       usersclient.NewFake(knownUser),
 		logger:      logger,
 	}
 
 	router.POST("/sales", h.handleCreate)
 	router.PATCH("/sales/:id", h.handleUpdate)
+	router.GET("/sales/:id/history", h.handleHistory)
 	router.GET("/sales", h.handleList)
 
 	// 1. POST /sales
@@ -209,13 +319,14 @@ func TestIntegration_FlujoCompleto(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &created)
 	require.NoError(t, err)
 	require.Equal(t, "abc123", created.UserID)
-	require.Equal(t, float32(150), created.Amount)
+	require.Equal(t, ars(150), created.Amount)
 	require.Equal(t, "pending", created.Estado)
 
 	// 2. PATCH /sales/:id
 	updateBody := `{"estado": "approved"}`
 	reqPatch := httptest.NewRequest(http.MethodPatch, "/sales/"+created.ID, strings.NewReader(updateBody))
 	reqPatch.Header.Set("Content-Type", "application/json")
+	reqPatch.Header.Set("X-Actor", "backoffice")
 	recPatch := httptest.NewRecorder()
 	router.ServeHTTP(recPatch, reqPatch)
 
@@ -236,6 +347,1274 @@ func TestIntegration_FlujoCompleto(t *testing.T) {
 	assert.Contains(t, bodyResp, `"results"`)
 	assert.Contains(t, bodyResp, `"user_id":"abc123"`)
 	assert.Contains(t, bodyResp, `"estado":"approved"`)
+
+	// 4. GET /sales/:id/history
+	reqHist := httptest.NewRequest(http.MethodGet, "/sales/"+created.ID+"/history", nil)
+	recHist := httptest.NewRecorder()
+	router.ServeHTTP(recHist, reqHist)
+
+	require.Equal(t, http.StatusOK, recHist.Code)
+	var history struct {
+		Results []sale.StateChange `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(recHist.Body.Bytes(), &history))
+	require.Len(t, history.Results, 1)
+	assert.Equal(t, "pending", history.Results[0].From)
+	assert.Equal(t, "approved", history.Results[0].To)
+	assert.Equal(t, "backoffice", history.Results[0].Actor)
+	assert.Equal(t, 2, history.Results[0].Version)
 }
 
 //======================= flujo completo POST → PATCH → GET (happy path) =======================//
+
+//======================= CONCURRENCIA OPTIMISTA =======================//
+
+func TestUpdateSale_Version(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	setup := func() (*gin.Engine, *sale.Sale) {
+		router := gin.New()
+		service := sale.NewService(sale.NewLocalStorage())
+		s := createTestSale(service, "abc123", ars(150), "pending")
+		h := handler{
+			saleService: service,
+			users:       usersclient.NewFake(knownUser),
+			logger:      logger,
+		}
+		router.GET("/sales/:id", h.handleRead)
+		router.PATCH("/sales/:id", h.handleUpdate)
+		return router, s
+	}
+
+	t.Run("GET devuelve ETag y PATCH con If-Match vigente @200", func(t *testing.T) {
+		router, s := setup()
+
+		recGet := httptest.NewRecorder()
+		router.ServeHTTP(recGet, httptest.NewRequest(http.MethodGet, "/sales/"+s.ID, nil))
+		require.Equal(t, http.StatusOK, recGet.Code)
+		require.Equal(t, `"1"`, recGet.Header().Get("ETag"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sales/"+s.ID, strings.NewReader(`{"estado": "approved"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", recGet.Header().Get("ETag"))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("If-Match desactualizado @412", func(t *testing.T) {
+		router, s := setup()
+
+		req := httptest.NewRequest(http.MethodPatch, "/sales/"+s.ID, strings.NewReader(`{"estado": "approved"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"7"`)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	})
+
+	t.Run("version desactualizada en el body @409", func(t *testing.T) {
+		router, s := setup()
+
+		req := httptest.NewRequest(http.MethodPatch, "/sales/"+s.ID, strings.NewReader(`{"estado": "approved", "version": 7}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "version conflict")
+	})
+}
+
+//======================= CONCURRENCIA OPTIMISTA =======================//
+
+//======================= PAGINACIÓN =======================//
+
+func TestListSales_Pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	for _, amount := range []sale.Money{ars(300), ars(100), ars(200)} {
+		createTestSale(h.saleService, "abc123", amount, "pending")
+	}
+	router.GET("/sales", h.handleList)
+
+	var page struct {
+		Metadata   map[string]json.RawMessage `json:"metadata"`
+		Results    []sale.Sale                `json:"results"`
+		Total      int                        `json:"total"`
+		NextCursor string                     `json:"next_cursor"`
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?user_id=abc123&sort=amount&order=desc&limit=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Results, 2)
+	assert.Equal(t, ars(300), page.Results[0].Amount)
+	assert.Equal(t, ars(200), page.Results[1].Amount)
+	require.NotEmpty(t, page.NextCursor)
+	// la metadata cubre todas las ventas filtradas; "page", solo la página
+	assert.JSONEq(t, `3`, string(page.Metadata["quantity"]))
+	assert.JSONEq(t, `3`, string(page.Metadata["pending"]))
+	assert.JSONEq(t, `600.00`, string(page.Metadata["total_amount"]))
+	assert.JSONEq(t, `{"quantity":2,"totals":{"ARS":500.00}}`, string(page.Metadata["page"]))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?user_id=abc123&sort=amount&order=desc&limit=2&cursor="+page.NextCursor, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Results, 1)
+	assert.Equal(t, ars(100), page.Results[0].Amount)
+	assert.Empty(t, page.NextCursor)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?user_id=abc123&limit=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+//======================= PAGINACIÓN =======================//
+
+//======================= FILTROS =======================//
+
+func TestListSales_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	// las fechas rodean la creación, por si se cruza la medianoche UTC
+	from := time.Now().UTC().Format(time.DateOnly)
+	createTestSale(h.saleService, "abc123", ars(100), "pending")
+	createTestSale(h.saleService, "otro", ars(500), "pending")
+	to := time.Now().UTC().Format(time.DateOnly)
+	router.GET("/sales", h.handleList)
+
+	t.Run("sin user_id lista todas las ventas", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"total":2`)
+	})
+
+	t.Run("rango de montos y fechas", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		url := "/sales?min_amount=200&created_from=" + from + "&created_to=" + to
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"total":1`)
+		assert.Contains(t, rec.Body.String(), `"user_id":"otro"`)
+	})
+
+	t.Run("rango invertido @400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?min_amount=10&max_amount=1", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "filtro inválido")
+	})
+
+	t.Run("fecha mal formada @400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?created_from=ayer", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+//======================= FILTROS =======================//
+
+//======================= MONEDAS =======================//
+
+func TestListSales_Currencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	rates, err := sale.NewStaticRates("USD", map[string]*big.Rat{"ARS": big.NewRat(1000, 1)})
+	require.NoError(t, err)
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	h.saleService = sale.NewService(sale.NewLocalStorage(), sale.WithRateProvider(rates))
+	createTestSale(h.saleService, "abc123", ars(1000), "pending")
+	createTestSale(h.saleService, "abc123", ars(500), "pending")
+	createTestSale(h.saleService, "abc123", sale.NewMoney(250, "USD"), "pending")
+	router.GET("/sales", h.handleList)
+
+	get := func(url string) (*httptest.ResponseRecorder, map[string]json.RawMessage) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var body struct {
+			Metadata map[string]json.RawMessage `json:"metadata"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body.Metadata
+	}
+
+	t.Run("totales por moneda", func(t *testing.T) {
+		rec, metadata := get("/sales")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"ARS":1500.00,"USD":2.50}`, string(metadata["totals"]))
+		// con varias monedas no hay un único total, pero la clave sigue
+		require.Contains(t, metadata, "total_amount")
+		assert.JSONEq(t, `null`, string(metadata["total_amount"]))
+	})
+
+	t.Run("filtro por moneda conserva total_amount", func(t *testing.T) {
+		rec, metadata := get("/sales?currency=ars")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `1500.00`, string(metadata["total_amount"]))
+	})
+
+	t.Run("total convertido", func(t *testing.T) {
+		rec, metadata := get("/sales?convert_to=USD")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"amount":4.00,"currency":"USD"}`, string(metadata["converted_total"]))
+	})
+
+	t.Run("sin cotización @422", func(t *testing.T) {
+		rec, _ := get("/sales?convert_to=EUR")
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("moneda inválida @400", func(t *testing.T) {
+		rec, _ := get("/sales?convert_to=PESOS")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+//======================= MONEDAS =======================//
+
+//======================= ESTADÍSTICAS =======================//
+
+func TestSalesStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	createTestSale(h.saleService, "abc123", ars(100), "pending")
+	createTestSale(h.saleService, "abc123", ars(300), "approved")
+	createTestSale(h.saleService, "otro", ars(500), "pending")
+	router.GET("/sales/stats", h.handleStats)
+	router.GET("/sales/:id", h.handleRead)
+
+	t.Run("agrupado por usuario", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/stats?group_by=user", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var stats struct {
+			Total  struct{ Count int }
+			Groups []struct {
+				Key     string
+				Count   int
+				Amounts map[string]json.RawMessage
+			}
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+		assert.Equal(t, 3, stats.Total.Count)
+		require.Len(t, stats.Groups, 2)
+		assert.Equal(t, "abc123", stats.Groups[0].Key)
+		assert.Equal(t, 2, stats.Groups[0].Count)
+		assert.JSONEq(t, `{"count":2,"sum":400.00,"avg":200.00,"min":100.00,"max":300.00}`,
+			string(stats.Groups[0].Amounts["ARS"]))
+	})
+
+	t.Run("con filtros", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/stats?status=pending", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"count":2`)
+	})
+
+	t.Run("agrupamiento inválido @400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/stats?group_by=year", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "agrupamiento inválido")
+	})
+}
+
+//======================= ESTADÍSTICAS =======================//
+
+//======================= EXPORTACIÓN =======================//
+
+func TestExportSales(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	s1 := createTestSale(h.saleService, "abc123", sale.NewMoney(15050, sale.DefaultCurrency), "pending")
+	createTestSale(h.saleService, "otro", ars(500), "pending")
+	router.GET("/sales/export", h.handleExport)
+
+	t.Run("csv con filtros", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?user_id=abc123", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), "sales.csv")
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Equal(t, "id,user_id,estado,amount,amount_minor,currency,created_at,updated_at,version,deleted_at", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], s1.ID+",abc123,pending,150.50,15050,ARS,"), lines[1])
+	})
+
+	t.Run("csv vacío conserva el encabezado", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?user_id=nadie", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "id,user_id,estado,amount,amount_minor,currency,created_at,updated_at,version,deleted_at\n", rec.Body.String())
+	})
+
+	t.Run("csv neutraliza fórmulas", func(t *testing.T) {
+		evil := createTestSale(h.saleService, "=HYPERLINK(\"http://x\")", ars(1), "pending")
+		defer h.saleService.Purge(evil.ID)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?user_id="+url.QueryEscape(evil.UserID), nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[1], evil.ID+`,"'=HYPERLINK(""http://x"")",`), lines[1])
+	})
+
+	t.Run("csv con borradas incluye deleted_at", func(t *testing.T) {
+		gone := createTestSale(h.saleService, "borrado", ars(1), "pending")
+		require.NoError(t, h.saleService.Delete(gone.ID, nil, "backoffice"))
+		defer h.saleService.Purge(gone.ID)
+		deleted, err := h.saleService.GetWithDeleted(gone.ID)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?user_id=borrado&include_deleted=true", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasSuffix(lines[1], ","+deleted.DeletedAt.UTC().Format(time.RFC3339Nano)), lines[1])
+
+		// las vigentes dejan la columna vacía
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?user_id=abc123", nil))
+		assert.True(t, strings.HasSuffix(strings.TrimSpace(rec.Body.String()), ","), rec.Body.String())
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?format=ndjson", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			var s sale.Sale
+			require.NoError(t, json.Unmarshal([]byte(line), &s))
+			assert.NotEmpty(t, s.ID)
+		}
+	})
+
+	t.Run("formato desconocido @400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?format=xlsx", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("filtro inválido @400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/export?min_amount=10&max_amount=1", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+//======================= EXPORTACIÓN =======================//
+
+//======================= IMPORTACIÓN MASIVA =======================//
+
+func TestBulkCreateSales(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	post := func(router *gin.Engine, url, body string) (*httptest.ResponseRecorder, bulkReport) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+		var report bulkReport
+		_ = json.Unmarshal(rec.Body.Bytes(), &report)
+		return rec, report
+	}
+	newRouter := func() (*gin.Engine, *handler) {
+		router := gin.New()
+		h := newHandler(usersclient.NewFake(knownUser), logger)
+		router.POST("/sales/bulk", h.handleBulkCreate)
+		return router, &h
+	}
+	count := func(h *handler) int {
+		all, err := h.saleService.Find(sale.Filter{})
+		require.NoError(t, err)
+		return len(all)
+	}
+
+	t.Run("arreglo JSON válido @201", func(t *testing.T) {
+		router, h := newRouter()
+		rec, report := post(router, "/sales/bulk", `[
+			{"user_id": "abc123", "amount": 100},
+			{"user_id": "abc123", "amount_minor": 2550, "currency": "USD", "estado": "approved"}
+		]`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, bulkCreated, report.Results[1].Status)
+		assert.Contains(t, rec.Body.String(), `"estado":"approved"`)
+		assert.Equal(t, 2, count(h))
+	})
+
+	t.Run("NDJSON con filas inválidas @207", func(t *testing.T) {
+		router, h := newRouter()
+		body := `{"user_id": "abc123", "amount": 100}
+{"user_id": "no-existe", "amount": 100}
+{"user_id": "abc123", "amount": 0}
+{"user_id": "abc123", "amount": 100, "estado": "refunded"}
+{"user_id": 42, "amount": 100}
+`
+		rec, report := post(router, "/sales/bulk", body)
+		require.Equal(t, http.StatusMultiStatus, rec.Code)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 4, report.Failed)
+		assert.Equal(t, "el usuario no existe", report.Results[1].Error)
+		assert.Equal(t, 5, report.Results[4].Row)
+		assert.Equal(t, bulkFailed, report.Results[4].Status)
+		assert.Equal(t, 1, count(h))
+	})
+
+	t.Run("todo o nada no guarda si falla una fila @422", func(t *testing.T) {
+		router, h := newRouter()
+		rec, report := post(router, "/sales/bulk?atomic=true", `[
+			{"user_id": "abc123", "amount": 100},
+			{"user_id": "no-existe", "amount": 100}
+		]`)
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, bulkSkipped, report.Results[0].Status)
+		assert.Equal(t, bulkFailed, report.Results[1].Status)
+		assert.Equal(t, 0, count(h))
+
+		rec, report = post(router, "/sales/bulk?atomic=true", `[{"user_id": "abc123", "amount": 100}]`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.True(t, report.Atomic)
+		assert.Equal(t, 1, count(h))
+	})
+
+	t.Run("JSON mal formado o vacío @400", func(t *testing.T) {
+		router, _ := newRouter()
+		for _, body := range []string{``, `[]`, `[{"user_id": "abc123"`, `{"user_id": "abc123"} nope`} {
+			rec, _ := post(router, "/sales/bulk", body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+}
+
+//======================= IMPORTACIÓN MASIVA =======================//
+
+//======================= IDEMPOTENCIA =======================//
+
+func TestCreateSale_Idempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	users := usersclient.NewFake(knownUser)
+	h := newHandler(users, logger)
+	store := newIdempotencyStore(time.Hour)
+	router := gin.New()
+	router.POST("/sales", idempotent(store), h.handleCreate)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(idempotencyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	count := func() int {
+		all, err := h.saleService.Find(sale.Filter{})
+		require.NoError(t, err)
+		return len(all)
+	}
+
+	t.Run("reintento con la misma clave devuelve la misma venta", func(t *testing.T) {
+		first := post("k1", `{"user_id": "abc123", "amount": 100}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+
+		again := post("k1", `{"user_id": "abc123", "amount": 100}`)
+		require.Equal(t, http.StatusCreated, again.Code)
+		assert.Equal(t, first.Body.String(), again.Body.String())
+		assert.Equal(t, "true", again.Header().Get(replayedHeader))
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("misma clave con otro cuerpo @422", func(t *testing.T) {
+		rec := post("k1", `{"user_id": "abc123", "amount": 999}`)
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("errores 5xx no se guardan", func(t *testing.T) {
+		users.Err = usersclient.ErrUnavailable
+		require.Equal(t, http.StatusServiceUnavailable, post("k2", `{"user_id": "abc123", "amount": 5}`).Code)
+		users.Err = nil
+		require.Equal(t, http.StatusCreated, post("k2", `{"user_id": "abc123", "amount": 5}`).Code)
+		assert.Equal(t, 2, count())
+	})
+
+	t.Run("la clave vence pasada la ventana", func(t *testing.T) {
+		store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		defer func() { store.now = time.Now }()
+
+		require.Equal(t, http.StatusCreated, post("k1", `{"user_id": "abc123", "amount": 100}`).Code)
+		assert.Equal(t, 3, count())
+	})
+
+	t.Run("sin clave no hay deduplicación", func(t *testing.T) {
+		post("", `{"user_id": "abc123", "amount": 1}`)
+		post("", `{"user_id": "abc123", "amount": 1}`)
+		assert.Equal(t, 5, count())
+	})
+
+	t.Run("request en curso @409", func(t *testing.T) {
+		// simula una primera request con k3 que todavía no respondió
+		body := `{"user_id": "abc123", "amount": 1}`
+		sum := sha256.Sum256([]byte("POST /sales\n" + body))
+		_, ok := store.begin("k3", hex.EncodeToString(sum[:]))
+		require.True(t, ok)
+
+		require.Equal(t, http.StatusConflict, post("k3", body).Code)
+	})
+
+	t.Run("cuerpo demasiado grande @413", func(t *testing.T) {
+		body := `{"user_id": "abc123", "amount": 1, "pad": "` + strings.Repeat("x", maxIdempotencyBody) + `"}`
+		require.Equal(t, http.StatusRequestEntityTooLarge, post("k4", body).Code)
+		assert.Equal(t, 5, count())
+	})
+}
+
+func TestIdempotencyStore_Evict(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	store.maxEntries = 2
+
+	_, ok := store.begin("a", "fa")
+	require.True(t, ok)
+	_, ok = store.begin("b", "fb")
+	require.True(t, ok)
+	store.finish("b", http.StatusCreated, "application/json", nil)
+
+	// "a" sigue en curso, así que se olvida "b" aunque sea más nueva
+	_, ok = store.begin("c", "fc")
+	require.True(t, ok)
+	assert.Len(t, store.entries, 2)
+	assert.NotContains(t, store.entries, "b")
+
+	store.finish("a", http.StatusCreated, "application/json", nil)
+	store.finish("c", http.StatusCreated, "application/json", nil)
+	_, ok = store.begin("d", "fd")
+	require.True(t, ok)
+	assert.Len(t, store.entries, 2)
+	assert.NotContains(t, store.entries, "a")
+	assert.Equal(t, 2, store.order.Len())
+}
+
+//======================= IDEMPOTENCIA =======================//
+
+//======================= BORRADO =======================//
+
+func TestDeleteSale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	router.GET("/sales", h.handleList)
+	router.GET("/sales/:id", h.handleRead)
+	router.DELETE("/sales/:id", h.handleDelete)
+	router.DELETE("/admin/sales/:id", requireAdmin("secreto"), h.handlePurge)
+
+	do := func(method, url string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	s := createTestSale(h.saleService, "abc123", ars(100), "pending")
+
+	t.Run("If-Match desactualizado @412", func(t *testing.T) {
+		require.Equal(t, http.StatusPreconditionFailed, do(http.MethodDelete, "/sales/"+s.ID, "If-Match", `"7"`).Code)
+	})
+
+	t.Run("borrado lógico @204", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/sales/"+s.ID, "If-Match", `"1"`, actorHeader, "backoffice").Code)
+
+		history, err := h.saleService.History(s.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, sale.HistoryDeleted, history[0].To)
+		assert.Equal(t, "backoffice", history[0].Actor)
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/sales/"+s.ID).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/sales/"+s.ID).Code)
+		assert.Contains(t, do(http.MethodGet, "/sales").Body.String(), `"total":0`)
+
+		rec := do(http.MethodGet, "/sales/"+s.ID+"?include_deleted=true")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"deleted_at"`)
+		assert.Contains(t, do(http.MethodGet, "/sales?include_deleted=true").Body.String(), `"total":1`)
+	})
+
+	t.Run("purga solo para administradores", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodDelete, "/admin/sales/"+s.ID).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/admin/sales/"+s.ID, adminHeader, "otro").Code)
+
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/sales/"+s.ID, adminHeader, "secreto").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/sales/"+s.ID+"?include_deleted=true").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/sales/"+s.ID, adminHeader, "secreto").Code)
+	})
+
+	t.Run("sin token configurado la purga está deshabilitada", func(t *testing.T) {
+		r := gin.New()
+		r.DELETE("/admin/sales/:id", requireAdmin(""), h.handlePurge)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/admin/sales/x", nil)
+		req.Header.Set(adminHeader, "")
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+//======================= BORRADO =======================//
+
+//======================= WEBHOOKS =======================//
+
+func TestWebhooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	var received []*http.Request
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	storage := sale.NewLocalStorage()
+	dispatcher := sale.NewDispatcher(storage)
+	// el receptor escucha en loopback
+	webhooks := webhook.NewService(webhook.NewMemoryStore(), webhook.WithPrivateHosts(true))
+	dispatcher.Subscribe("webhooks", webhooks.Handle)
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	h.saleService = sale.NewService(storage)
+	h.webhooks = webhooks
+	router.POST("/sales", h.handleCreate)
+	hooks := router.Group("/webhooks", requireAdmin("secreto"))
+	hooks.POST("", h.handleRegisterWebhook)
+	hooks.GET("/dead-letters", h.handleDeadLetters)
+	hooks.GET("/:id", h.handleReadWebhook)
+	hooks.GET("/:id/deliveries", h.handleWebhookDeliveries)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(adminHeader, "secreto")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("solo para administradores", func(t *testing.T) {
+		for _, path := range []string{"/webhooks/dead-letters", "/webhooks/nope", "/webhooks/nope/deliveries"} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"`+receiver.URL+`"}`)))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("url inválida @400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/webhooks", `{"url":"ftp://x"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/webhooks", `{"url":"http://x","events":["SaleDeleted"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/webhooks", `{}`).Code)
+	})
+
+	t.Run("red privada @400", func(t *testing.T) {
+		strict := webhook.NewService(webhook.NewMemoryStore())
+		r := gin.New()
+		r.POST("/webhooks", (&handler{webhooks: strict}).handleRegisterWebhook)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"http://169.254.169.254/latest/meta-data"}`)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("webhook inexistente @404", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/webhooks/nope/deliveries", "").Code)
+	})
+
+	t.Run("registro y entrega firmada", func(t *testing.T) {
+		rec := do(http.MethodPost, "/webhooks", `{"url":"`+receiver.URL+`","events":["SaleCreated"]}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var w webhook.Webhook
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &w))
+		require.NotEmpty(t, w.Secret)
+
+		rec = do(http.MethodGet, "/webhooks/"+w.ID, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), w.Secret)
+
+		require.Equal(t, http.StatusCreated, do(http.MethodPost, "/sales", `{"user_id":"abc123","amount":"10.00"}`).Code)
+		_, err := dispatcher.Dispatch(context.Background())
+		require.NoError(t, err)
+		n, err := webhooks.Deliver(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.Len(t, received, 1)
+		assert.NotEmpty(t, received[0].Header.Get(webhook.SignatureHeader))
+
+		rec = do(http.MethodGet, "/webhooks/"+w.ID+"/deliveries", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp struct {
+			WebhookID string             `json:"webhook_id"`
+			Results   []webhook.Delivery `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, w.ID, resp.WebhookID)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, webhook.StatusSucceeded, resp.Results[0].Status)
+		assert.Equal(t, sale.SaleCreated, resp.Results[0].EventType)
+
+		assert.Contains(t, do(http.MethodGet, "/webhooks/dead-letters", "").Body.String(), `"results":[]`)
+	})
+}
+
+//======================= WEBHOOKS =======================//
+
+//======================= STREAM =======================//
+
+func TestSalesStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	storage := sale.NewLocalStorage()
+	dispatcher := sale.NewDispatcher(storage)
+	broker := stream.NewBroker()
+	dispatcher.Subscribe("stream", broker.Publish)
+
+	router := gin.New()
+	h := newHandler(usersclient.NewFake(knownUser), logger)
+	h.saleService = sale.NewService(storage)
+	h.broker = broker
+	h.heartbeat = 20 * time.Millisecond
+	router.GET("/sales/stream", h.handleStream)
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// open conecta al stream y devuelve un lector de sus líneas
+	open := func(t *testing.T, url string, header ...string) (*bufio.Reader, *http.Response) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+url, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return bufio.NewReader(resp.Body), resp
+	}
+	// next devuelve el próximo mensaje (sin comentarios) como campo → valor
+	next := func(t *testing.T, r *bufio.Reader) map[string]string {
+		msg := map[string]string{}
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimRight(line, "\n")
+			if line == "" {
+				if len(msg) > 0 {
+					return msg
+				}
+				continue
+			}
+			field, value, _ := strings.Cut(line, ":")
+			if field == "" {
+				msg["comment"] = strings.TrimSpace(value)
+				return msg
+			}
+			msg[field] = strings.TrimSpace(value)
+		}
+	}
+	emit := func(userID string) *sale.Sale {
+		s := createTestSale(h.saleService, userID, ars(100), "pending")
+		_, err := dispatcher.Dispatch(context.Background())
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Last-Event-ID inválido @400", func(t *testing.T) {
+		_, resp := open(t, "/sales/stream", lastEventIDHeader, "abc")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("empuja ventas del usuario y heartbeats", func(t *testing.T) {
+		r, resp := open(t, "/sales/stream?user_id=abc123")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, map[string]string{"retry": "3000"}, next(t, r))
+
+		emit("otro")
+		s := emit("abc123")
+
+		msg := next(t, r)
+		for msg["comment"] != "" {
+			msg = next(t, r)
+		}
+		assert.Equal(t, "2", msg["id"])
+		assert.Equal(t, string(sale.SaleCreated), msg["event"])
+		var e sale.Event
+		require.NoError(t, json.Unmarshal([]byte(msg["data"]), &e))
+		assert.Equal(t, s.ID, e.SaleID)
+
+		assert.Equal(t, "heartbeat", next(t, r)["comment"])
+	})
+
+	t.Run("retoma desde Last-Event-ID", func(t *testing.T) {
+		r, _ := open(t, "/sales/stream", lastEventIDHeader, "1")
+		next(t, r) // retry
+		assert.Equal(t, "2", next(t, r)["id"])
+
+		r, _ = open(t, "/sales/stream?last_event_id=7")
+		next(t, r) // retry
+		assert.Equal(t, resetEvent, next(t, r)["event"])
+	})
+}
+
+//======================= STREAM =======================//
+
+//======================= SALUD =======================//
+
+func TestHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	var usersDown atomic.Bool
+	checker := health.NewChecker()
+	checker.Register("storage", func(context.Context) error { return nil })
+	checker.Register("users_service", func(context.Context) error {
+		if usersDown.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	router := gin.New()
+	cfg := config.Default()
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   usersclient.NewFake(knownUser),
+		Logger:  logger,
+	}, WithHealth(checker))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+	readyz := func(t *testing.T, code int) health.Report {
+		rec := get("/readyz")
+		require.Equal(t, code, rec.Code)
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return report
+	}
+
+	t.Run("listo @200", func(t *testing.T) {
+		report := readyz(t, http.StatusOK)
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, health.StatusUp, report.Checks["storage"].Status)
+	})
+
+	t.Run("dependencia caída @503", func(t *testing.T) {
+		usersDown.Store(true)
+		defer usersDown.Store(false)
+
+		report := readyz(t, http.StatusServiceUnavailable)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.StatusUp, report.Checks["storage"].Status)
+		assert.Equal(t, health.StatusDown, report.Checks["users_service"].Status)
+		// el detalle del error no se expone sin autenticación
+		assert.Equal(t, health.MessageFailed, report.Checks["users_service"].Error)
+
+		// liveness no depende de las dependencias
+		rec := get("/healthz")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+	})
+
+	t.Run("sin checks siempre está listo", func(t *testing.T) {
+		r := gin.New()
+		InitRoutes(r, &cfg, Dependencies{Storage: sale.NewLocalStorage(), Users: usersclient.NewFake(), Logger: logger})
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+//======================= SALUD =======================//
+
+//======================= READINESS =======================//
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	var ready atomic.Bool
+	ready.Store(true)
+	checker := health.NewChecker()
+	checker.Register("storage", func(context.Context) error { return nil })
+	router := gin.New()
+	cfg := config.Default()
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   usersclient.NewFake(knownUser),
+		Logger:  logger,
+	}, WithReadiness(ready.Load), WithHealth(checker))
+
+	readyz := func(t *testing.T, code int) health.Report {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		require.Equal(t, code, rec.Code)
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return report
+	}
+
+	t.Run("listo @200", func(t *testing.T) {
+		report := readyz(t, http.StatusOK)
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Equal(t, health.StatusUp, report.Checks["lifecycle"].Status)
+		assert.Equal(t, health.StatusUp, report.Checks["storage"].Status)
+	})
+
+	t.Run("apagándose @503", func(t *testing.T) {
+		ready.Store(false)
+		report := readyz(t, http.StatusServiceUnavailable)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.StatusDown, report.Checks["lifecycle"].Status)
+		assert.Equal(t, health.StatusUp, report.Checks["storage"].Status)
+	})
+}
+
+//======================= READINESS =======================//
+
+//======================= METRICAS =======================//
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	router := gin.New()
+	cfg := config.Default()
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   usersclient.NewFake(knownUser),
+		Logger:  logger,
+	})
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/sales", `{"user_id": "`+knownUser.ID+`", "amount": 150.50}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created sale.Sale
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, http.StatusOK, send(http.MethodPatch, "/sales/"+created.ID, `{"estado": "approved"}`).Code)
+	require.Equal(t, http.StatusCreated, send(http.MethodPost, "/sales", `{"user_id": "`+knownUser.ID+`", "amount": 50}`).Code)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/sales/nope", "").Code)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/no-existe", "").Code)
+
+	rec = send(http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+
+	t.Run("requests por ruta y status", func(t *testing.T) {
+		assert.Contains(t, body, "# TYPE http_requests_total counter\n")
+		assert.Contains(t, body, `http_requests_total{method="POST",route="/sales",status="201"} 2`+"\n")
+		assert.Contains(t, body, `http_requests_total{method="PATCH",route="/sales/:id",status="200"} 1`+"\n")
+		// la plantilla, no el id
+		assert.Contains(t, body, `http_requests_total{method="GET",route="/sales/:id",status="404"} 1`+"\n")
+		assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`+"\n")
+		assert.NotContains(t, body, created.ID)
+	})
+
+	t.Run("latencia como histograma", func(t *testing.T) {
+		assert.Contains(t, body, "# TYPE http_request_duration_seconds histogram\n")
+		assert.Contains(t, body, `http_request_duration_seconds_bucket{method="POST",route="/sales",status="201",le="+Inf"} 2`+"\n")
+		assert.Contains(t, body, `http_request_duration_seconds_count{method="POST",route="/sales",status="201"} 2`+"\n")
+	})
+
+	t.Run("contadores de negocio", func(t *testing.T) {
+		assert.Contains(t, body, `sales_created_total{currency="ARS"} 2`+"\n")
+		assert.Contains(t, body, `sales_approved_total{currency="ARS"} 1`+"\n")
+		assert.Contains(t, body, `sales_amount_total{currency="ARS"} 200.5`+"\n")
+		assert.Contains(t, body, "# TYPE sales_rejected_total counter\n")
+		assert.NotContains(t, body, "sales_rejected_total{")
+	})
+}
+
+//======================= METRICAS =======================//
+
+//======================= CACHE DE USUARIOS =======================//
+
+func TestUsersCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := zap.NewDevelopment()
+
+	fake := usersclient.NewFake(knownUser)
+	cache := usersclient.NewCachedClient(fake, 10, time.Minute, time.Second)
+	router := gin.New()
+	cfg := config.Default()
+	cfg.AdminToken = "secreto"
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   cache,
+		Logger:  logger,
+	}, WithUsersCache(cache))
+
+	send := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(adminHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	create := func() {
+		rec := send(http.MethodPost, "/sales", `{"user_id": "`+knownUser.ID+`", "amount": 10}`, "")
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	create()
+	create()
+	require.Equal(t, 1, fake.Calls())
+
+	t.Run("contadores en /metrics", func(t *testing.T) {
+		body := send(http.MethodGet, "/metrics", "", "").Body.String()
+		assert.Contains(t, body, "\nusers_cache_hits_total 1\n")
+		assert.Contains(t, body, "\nusers_cache_misses_total 1\n")
+		assert.Contains(t, body, "\nusers_cache_evictions_total 0\n")
+		assert.Contains(t, body, "\nusers_cache_entries 1\n")
+	})
+
+	t.Run("invalidar requiere token de admin", func(t *testing.T) {
+		rec := send(http.MethodDelete, "/admin/users/"+knownUser.ID+"/cache", "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		create()
+		assert.Equal(t, 1, fake.Calls())
+	})
+
+	t.Run("invalidar fuerza una nueva consulta @204", func(t *testing.T) {
+		rec := send(http.MethodDelete, "/admin/users/"+knownUser.ID+"/cache", "", "secreto")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		create()
+		assert.Equal(t, 2, fake.Calls())
+	})
+}
+
+//======================= CACHE DE USUARIOS =======================//
+
+//======================= REQUEST ID =======================//
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+
+	// servicio de usuarios que recuerda el X-Request-ID recibido
+	received := make(chan string, 1)
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(requestid.Header)
+		if r.URL.Path != "/users/"+knownUser.ID {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"id":"abc123","name":"nico"}`))
+	}))
+	defer users.Close()
+
+	router := gin.New()
+	cfg := config.Default()
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   usersclient.New(users.URL, usersclient.WithRetries(0, 0)),
+		Logger:  zap.New(core),
+	})
+
+	create := func(userID, requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(`{"user_id": "`+userID+`", "amount": 10}`))
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set(requestid.Header, requestID)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	served := func(t *testing.T, id string) observer.LoggedEntry {
+		entries := logs.FilterMessage("request served").FilterField(zap.String("request_id", id)).TakeAll()
+		require.Len(t, entries, 1)
+		return entries[0]
+	}
+
+	t.Run("se respeta el ID del cliente y se reenvía al servicio de usuarios", func(t *testing.T) {
+		rec := create(knownUser.ID, "req-123")
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "req-123", rec.Header().Get(requestid.Header))
+		assert.Equal(t, "req-123", <-received)
+
+		fields := served(t, "req-123").ContextMap()
+		assert.Equal(t, http.MethodPost, fields["method"])
+		assert.Equal(t, "/sales", fields["route"])
+		assert.EqualValues(t, http.StatusCreated, fields["status"])
+		assert.Contains(t, fields, "latency")
+	})
+
+	t.Run("sin ID se asigna uno", func(t *testing.T) {
+		rec := create(knownUser.ID, "")
+		require.Equal(t, http.StatusCreated, rec.Code)
+		id := rec.Header().Get(requestid.Header)
+		assert.True(t, requestid.Valid(id))
+		assert.Equal(t, id, <-received)
+		served(t, id)
+	})
+
+	t.Run("un ID inválido se reemplaza", func(t *testing.T) {
+		rec := create(knownUser.ID, "con espacios")
+		id := rec.Header().Get(requestid.Header)
+		assert.NotEqual(t, "con espacios", id)
+		assert.True(t, requestid.Valid(id))
+		<-received
+	})
+
+	t.Run("los logs del handler llevan el ID", func(t *testing.T) {
+		rec := create("boom", "req-456")
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		<-received
+
+		warnings := logs.FilterMessage("user lookup failed").TakeAll()
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "req-456", warnings[0].ContextMap()["request_id"])
+		assert.Equal(t, zap.ErrorLevel, served(t, "req-456").Level)
+	})
+}
+
+//======================= REQUEST ID =======================//
+
+//======================= PÁNICOS =======================//
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := gin.New()
+	cfg := config.Default()
+	InitRoutes(router, &cfg, Dependencies{
+		Storage: sale.NewLocalStorage(),
+		Users:   usersclient.NewFake(knownUser),
+		Logger:  zap.New(core),
+	})
+	// los middlewares de InitRoutes también cubren las rutas agregadas después
+	router.GET("/boom", func(*gin.Context) { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(requestid.Header, "req-789")
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	t.Run("el pánico queda en el access log", func(t *testing.T) {
+		entries := logs.FilterMessage("request served").FilterField(zap.String("request_id", "req-789")).TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, zap.ErrorLevel, entries[0].Level)
+		assert.EqualValues(t, http.StatusInternalServerError, entries[0].ContextMap()["status"])
+	})
+
+	t.Run("el pánico se cuenta en las métricas", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Contains(t, rec.Body.String(), `http_requests_total{method="GET",route="/boom",status="500"} 1`+"\n")
+	})
+}
+
+//======================= PÁNICOS =======================//
+
+//======================= OPCIONES =======================//
+
+func TestInitRoutes_Options(t *testing.T) {
+	t.Run("sin configuración usa los valores por defecto", func(t *testing.T) {
+		o := defaultOptions(nil)
+		assert.Equal(t, DefaultIdempotencyWindow, o.idempotencyWindow)
+		assert.Equal(t, DefaultStreamHeartbeat, o.streamHeartbeat)
+		assert.Empty(t, o.adminToken)
+	})
+
+	t.Run("las opciones pisan a la configuración", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.AdminToken = "de-config"
+		cfg.StreamHeartbeat = time.Second
+		o := defaultOptions(&cfg)
+		for _, opt := range []Option{WithAdminToken("de-opcion"), WithIdempotencyWindow(time.Minute)} {
+			opt(&o)
+		}
+		assert.Equal(t, "de-opcion", o.adminToken)
+		assert.Equal(t, time.Minute, o.idempotencyWindow)
+		assert.Equal(t, time.Second, o.streamHeartbeat)
+	})
+
+	t.Run("token de admin por opción", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		logger, _ := zap.NewDevelopment()
+		router := gin.New()
+		InitRoutes(router, nil, Dependencies{
+			Storage: sale.NewLocalStorage(),
+			Users:   usersclient.NewFake(knownUser),
+			Logger:  logger,
+		}, WithAdminToken("secreto"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/sales/nope", nil)
+		req.Header.Set(adminHeader, "secreto")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+//======================= OPCIONES =======================//
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyHeader carries the client-chosen key that identifies retries
+// of the same request.
+const idempotencyHeader = "Idempotency-Key"
+
+// replayedHeader is set on responses replayed from the idempotency store.
+const replayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLen bounds the keys accepted, to keep the store small.
+const maxIdempotencyKeyLen = 255
+
+// maxIdempotencyBody bounds the request bodies read to fingerprint them,
+// since the whole body is kept in memory.
+const maxIdempotencyBody = 1 << 20
+
+// maxIdempotencyEntries bounds the keys kept at once. Past it, the oldest
+// finished keys are forgotten before their window ends.
+const maxIdempotencyEntries = 10000
+
+// DefaultIdempotencyWindow is how long the first response to a key is
+// kept for replay.
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// idempotencyEntry is the outcome of the first request made with a key.
+type idempotencyEntry struct {
+	fingerprint string
+	done        bool // false while the first request is still running
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+	elem        *list.Element // position in idempotencyStore.order
+}
+
+// idempotencyStore remembers responses by Idempotency-Key for a window.
+// It lives in memory, so keys are forgotten when the process restarts,
+// and it holds at most maxEntries keys.
+type idempotencyStore struct {
+	window     time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	order     *list.List // keys, oldest first
+	nextSweep time.Time
+}
+
+func newIdempotencyStore(window time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		window:     window,
+		maxEntries: maxIdempotencyEntries,
+		now:        time.Now,
+		entries:    map[string]*idempotencyEntry{},
+		order:      list.New(),
+	}
+}
+
+// begin reserves key for a request with the given fingerprint. If the key
+// is already in use it returns a copy of its entry and false instead.
+func (s *idempotencyStore) begin(key, fingerprint string) (idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok {
+		if now.Before(e.expires) {
+			return *e, false
+		}
+		s.remove(key)
+	}
+	s.entries[key] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expires:     now.Add(s.window),
+		elem:        s.order.PushBack(key),
+	}
+	s.evict()
+	return idempotencyEntry{}, true
+}
+
+// finish records the response to replay for key.
+func (s *idempotencyStore) finish(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.done = true
+		e.status = status
+		e.contentType = contentType
+		e.body = body
+	}
+}
+
+// release forgets key, so the next request with it runs again.
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+}
+
+// remove forgets key. mu must be held.
+func (s *idempotencyStore) remove(key string) {
+	if e, ok := s.entries[key]; ok {
+		s.order.Remove(e.elem)
+		delete(s.entries, key)
+	}
+}
+
+// evict forgets the oldest finished keys while the store holds more than
+// maxEntries. Keys still in use are kept, so their requests can finish.
+// mu must be held.
+func (s *idempotencyStore) evict() {
+	for el := s.order.Front(); el != nil && len(s.entries) > s.maxEntries; {
+		next := el.Next()
+		if key := el.Value.(string); s.entries[key].done {
+			s.remove(key)
+		}
+		el = next
+	}
+}
+
+// sweep drops expired entries, at most once a minute. mu must be held.
+func (s *idempotencyStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(time.Minute)
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			s.remove(key)
+		}
+	}
+}
+
+// idempotent makes a handler safe to retry: the first response to each
+// Idempotency-Key is stored and replayed for later requests with the same
+// key and body. Reusing a key with a different body is rejected with 422,
+// and a retry while the first request is still running gets 409. Bodies
+// over maxIdempotencyBody are rejected with 413.
+// Server errors (5xx) are not stored, so they can be retried.
+// Requests without the header are not affected.
+func idempotent(store *idempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key demasiado larga"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotencyBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "el cuerpo de la request es demasiado grande"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.FullPath()+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		entry, ok := store.begin(key, fingerprint)
+		if !ok {
+			switch {
+			case entry.fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key ya usada con otra request"})
+			case !entry.done:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "hay una request en curso con esta Idempotency-Key"})
+			default:
+				c.Header(replayedHeader, "true")
+				c.Data(entry.status, entry.contentType, entry.body)
+				c.Abort()
+			}
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		defer func() {
+			// si el handler entró en pánico no hay respuesta que guardar
+			if r := recover(); r != nil {
+				store.release(key)
+				panic(r)
+			}
+			if rec.Status() >= http.StatusInternalServerError {
+				store.release(key)
+				return
+			}
+			store.finish(key, rec.Status(), rec.Header().Get("Content-Type"), rec.body.Bytes())
+		}()
+		c.Next()
+	}
+}
+
+// recordingWriter keeps a copy of the response body it writes.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
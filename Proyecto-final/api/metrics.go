@@ -0,0 +1,91 @@
+package api
+
+import (
+	"sales-api/internal/metrics"
+	"sales-api/internal/sale"
+	"sales-api/internal/usersclient"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths
+// do not each get their own series.
+const unmatchedRoute = "unmatched"
+
+// instrument records the count and latency of every request, by method,
+// route template and status.
+func instrument(reg *metrics.Registry) gin.HandlerFunc {
+	requests := reg.NewCounterVec("http_requests_total",
+		"Total HTTP requests by method, route and status.", "method", "route", "status")
+	latency := reg.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency in seconds by method, route and status.", metrics.DefBuckets, "method", "route", "status")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// la plantilla (/sales/:id), no el path, para acotar las series
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		requests.Inc(c.Request.Method, route, status)
+		latency.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+	}
+}
+
+// saleMetrics implements sale.Metrics with business counters by currency.
+type saleMetrics struct {
+	created  *metrics.CounterVec
+	approved *metrics.CounterVec
+	rejected *metrics.CounterVec
+	amount   *metrics.CounterVec
+}
+
+func newSaleMetrics(reg *metrics.Registry) *saleMetrics {
+	return &saleMetrics{
+		created:  reg.NewCounterVec("sales_created_total", "Sales created by currency.", "currency"),
+		approved: reg.NewCounterVec("sales_approved_total", "Sales approved by currency.", "currency"),
+		rejected: reg.NewCounterVec("sales_rejected_total", "Sales rejected by currency.", "currency"),
+		amount:   reg.NewCounterVec("sales_amount_total", "Amount of the sales created, in major units, by currency.", "currency"),
+	}
+}
+
+func (m *saleMetrics) SaleCreated(s sale.Sale) {
+	currency := s.Amount.Currency
+	m.created.Inc(currency)
+	if amount, err := strconv.ParseFloat(s.Amount.String(), 64); err == nil && amount > 0 {
+		m.amount.Add(amount, currency)
+	}
+	m.state(s)
+}
+
+func (m *saleMetrics) SaleStateChanged(s sale.Sale, _ string) {
+	m.state(s)
+}
+
+// state counts s if it is in a final state.
+func (m *saleMetrics) state(s sale.Sale) {
+	switch s.Estado {
+	case sale.StateApproved:
+		m.approved.Inc(s.Amount.Currency)
+	case sale.StateRejected:
+		m.rejected.Inc(s.Amount.Currency)
+	}
+}
+
+// registerCacheMetrics exposes the counters of the users cache, read on
+// every scrape.
+func registerCacheMetrics(reg *metrics.Registry, cache *usersclient.CachedClient) {
+	reg.NewCounterFunc("users_cache_hits_total", "Users cache lookups answered from the cache.",
+		func() float64 { return float64(cache.Stats().Hits) })
+	reg.NewCounterFunc("users_cache_misses_total", "Users cache lookups that called the users service.",
+		func() float64 { return float64(cache.Stats().Misses) })
+	reg.NewCounterFunc("users_cache_evictions_total", "Users cache entries evicted to make room.",
+		func() float64 { return float64(cache.Stats().Evictions) })
+	reg.NewGaugeFunc("users_cache_entries", "Users currently cached.",
+		func() float64 { return float64(cache.Stats().Entries) })
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"platform/health"
+	"sales-api/internal/config"
+	"sales-api/internal/metrics"
+	"sales-api/internal/sale"
+	"sales-api/internal/stream"
+	"sales-api/internal/usersclient"
+	"sales-api/internal/webhook"
+	"time"
+)
+
+// Option configures InitRoutes.
+type Option func(*options)
+
+type options struct {
+	service           []sale.Option
+	idempotencyWindow time.Duration
+	adminToken        string
+	webhooks          *webhook.Service
+	broker            *stream.Broker
+	streamHeartbeat   time.Duration
+	health            *health.Checker
+	ready             func() bool
+	metrics           *metrics.Registry
+	cache             *usersclient.CachedClient
+}
+
+// defaultOptions returns the options taken from cfg, or the defaults if
+// cfg is nil.
+func defaultOptions(cfg *config.Config) options {
+	o := options{
+		idempotencyWindow: DefaultIdempotencyWindow,
+		streamHeartbeat:   DefaultStreamHeartbeat,
+		health:            health.NewChecker(),
+		metrics:           metrics.NewRegistry(),
+	}
+	if cfg != nil {
+		o.idempotencyWindow = cfg.IdempotencyWindow
+		o.adminToken = cfg.AdminToken
+		o.streamHeartbeat = cfg.StreamHeartbeat
+	}
+	return o
+}
+
+// WithServiceOptions passes opts on to sale.NewService.
+func WithServiceOptions(opts ...sale.Option) Option {
+	return func(o *options) {
+		o.service = append(o.service, opts...)
+	}
+}
+
+// WithIdempotencyWindow sets how long responses to POST /sales are kept
+// for replay by Idempotency-Key, overriding cfg.IdempotencyWindow. The
+// default is DefaultIdempotencyWindow.
+func WithIdempotencyWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.idempotencyWindow = d
+	}
+}
+
+// WithAdminToken enables the administrative endpoints (/admin and
+// /webhooks) for requests carrying token in the X-Admin-Token header,
+// overriding cfg.AdminToken. Without a token they are disabled.
+func WithAdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+// WithDispatcher hands the sale service the dispatcher of its outbox, so
+// domain events are delivered as soon as they are written. The caller is
+// responsible for running it.
+func WithDispatcher(d *sale.Dispatcher) Option {
+	return func(o *options) {
+		o.service = append(o.service, sale.WithDispatcher(d))
+	}
+}
+
+// WithWebhooks enables the /webhooks endpoints on top of svc. The caller
+// is responsible for subscribing it to the dispatcher and running it.
+func WithWebhooks(svc *webhook.Service) Option {
+	return func(o *options) {
+		o.webhooks = svc
+	}
+}
+
+// WithBroker enables GET /sales/stream, fed by b. The caller is
+// responsible for subscribing it to the dispatcher.
+func WithBroker(b *stream.Broker) Option {
+	return func(o *options) {
+		o.broker = b
+	}
+}
+
+// WithStreamHeartbeat sets how often GET /sales/stream writes a heartbeat,
+// overriding cfg.StreamHeartbeat. The default is DefaultStreamHeartbeat.
+func WithStreamHeartbeat(d time.Duration) Option {
+	return func(o *options) {
+		o.streamHeartbeat = d
+	}
+}
+
+// WithHealth makes GET /readyz report the checks of c. Without it the
+// service is ready unless WithReadiness says otherwise.
+func WithHealth(c *health.Checker) Option {
+	return func(o *options) {
+		o.health = c
+	}
+}
+
+// WithReadiness makes GET /readyz answer 503 while ready reports false,
+// e.g. lifecycle.App.Ready during shutdown. It is reported as the
+// "lifecycle" check of the checker given to WithHealth.
+func WithReadiness(ready func() bool) Option {
+	return func(o *options) {
+		o.ready = ready
+	}
+}
+
+// WithMetrics makes GET /metrics expose reg, so the caller can add its own
+// metrics to it. Without it InitRoutes uses a registry of its own.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(o *options) {
+		o.metrics = reg
+	}
+}
+
+// WithUsersCache exposes the counters of c on GET /metrics and enables
+// DELETE /admin/users/:id/cache, which drops a user from it. c should be
+// the client given in Dependencies.Users.
+func WithUsersCache(c *usersclient.CachedClient) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
@@ -2,31 +2,80 @@ package api
 
 import (
 	"net/http"
+	"platform/health"
+	"platform/logging"
+	"sales-api/internal/config"
 	"sales-api/internal/sale"
+	"sales-api/internal/usersclient"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
+// Dependencies are the collaborators the handlers are wired to. They are
+// built by the caller, from the configuration.
+type Dependencies struct {
+	Storage sale.Storage
+	Users   usersclient.Client
+	Logger  *zap.Logger
+}
+
 // InitRoutes registers all sale CRUD endpoints on the given Gin engine.
-// It initializes the storage, service, and handler, then binds each HTTP
-// method and path to the appropriate handler function.
-func InitRoutes(e *gin.Engine) {
-	storage := sale.NewLocalStorage()
-	service := sale.NewService(storage)
-	logger, _ := zap.NewProduction()
-	restyClient := &realHTTPClient{client: resty.New()}
+// It initializes the service and handler on top of deps, configured by
+// cfg, then binds each HTTP method and path to the appropriate handler
+// function. A nil cfg leaves every setting at its default; opts override
+// cfg.
+func InitRoutes(e *gin.Engine, cfg *config.Config, deps Dependencies, opts ...Option) {
+	o := defaultOptions(cfg)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ready != nil {
+		o.health.Register("lifecycle", health.ReadyCheck(o.ready))
+	}
+
+	// Recovery va después del log y las métricas, para que un pánico se
+	// registre y cuente como 500
+	e.Use(logging.Middleware(deps.Logger), instrument(o.metrics), gin.Recovery())
+	service := sale.NewService(deps.Storage, append(o.service, sale.WithMetrics(newSaleMetrics(o.metrics)))...)
 	h := handler{
 		saleService: service,
-		httpClient:  restyClient,
-		logger:      logger,
+		users:       deps.Users,
+		logger:      deps.Logger,
+		webhooks:    o.webhooks,
+		broker:      o.broker,
+		heartbeat:   o.streamHeartbeat,
 	}
 
-	e.POST("/sales", h.handleCreate)
+	e.POST("/sales", idempotent(newIdempotencyStore(o.idempotencyWindow)), h.handleCreate)
+	e.POST("/sales/bulk", h.handleBulkCreate)
+	e.GET("/sales/stats", h.handleStats)
+	e.GET("/sales/export", h.handleExport)
+	if o.broker != nil {
+		e.GET("/sales/stream", h.handleStream)
+	}
+	e.GET("/sales/:id", h.handleRead)
 	e.PATCH("/sales/:id", h.handleUpdate)
-	//e.DELETE("/sales/:id", h.handleDelete)
+	e.GET("/sales/:id/history", h.handleHistory)
+	e.DELETE("/sales/:id", h.handleDelete)
+	e.DELETE("/admin/sales/:id", requireAdmin(o.adminToken), h.handlePurge)
+	if o.cache != nil {
+		registerCacheMetrics(o.metrics, o.cache)
+		e.DELETE("/admin/users/:id/cache", requireAdmin(o.adminToken), handleInvalidateUser(o.cache))
+	}
+
+	if o.webhooks != nil {
+		// los webhooks hacen requests salientes y exponen sus respuestas: solo administradores
+		hooks := e.Group("/webhooks", requireAdmin(o.adminToken))
+		hooks.POST("", h.handleRegisterWebhook)
+		hooks.GET("/dead-letters", h.handleDeadLetters)
+		hooks.GET("/:id", h.handleReadWebhook)
+		hooks.GET("/:id/deliveries", h.handleWebhookDeliveries)
+	}
 
+	e.GET("/healthz", health.Liveness)
+	e.GET("/readyz", health.Readiness(o.health))
+	e.GET("/metrics", gin.WrapH(o.metrics))
 	e.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "pong",
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sales-api/internal/sale"
+	"sales-api/internal/stream"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultStreamHeartbeat is how often GET /sales/stream writes a comment
+// to keep idle connections (and the proxies in between) open.
+const DefaultStreamHeartbeat = 15 * time.Second
+
+// lastEventIDHeader is sent by EventSource clients when they reconnect.
+const lastEventIDHeader = "Last-Event-ID"
+
+// streamRetry is the reconnection delay suggested to clients, in ms.
+const streamRetry = 3000
+
+// resetEvent tells the client that events were lost since its
+// Last-Event-ID, so it must reload the sales it shows (e.g. GET /sales).
+const resetEvent = "reset"
+
+// handleStream handles GET /sales/stream, pushing sale creations and state
+// transitions as Server-Sent Events. Each event is named after its type,
+// carries the sale.Event as JSON and has its Seq as id, so a client that
+// reconnects with Last-Event-ID (header or last_event_id query parameter)
+// gets what it missed. user_id limits the stream to a user's sales.
+func (h *handler) handleStream(c *gin.Context) {
+	userID := c.Query("user_id")
+	match := func(e sale.Event) bool {
+		return userID == "" || e.Sale.UserID == userID
+	}
+
+	var (
+		sub      *stream.Subscription
+		missed   []sale.Event
+		complete = true
+	)
+	lastID := c.GetHeader(lastEventIDHeader)
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	if lastID != "" {
+		after, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID inválido"})
+			return
+		}
+		sub, missed, complete = h.broker.Resume(after, match)
+	} else {
+		sub = h.broker.Subscribe(match)
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// que los proxies no acumulen la respuesta
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// sse.Encode no sabe escribir un retry sin datos
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry); err != nil {
+		return
+	}
+	if !complete {
+		if err := sse.Encode(c.Writer, sse.Event{Event: resetEvent, Data: gin.H{"reason": "se perdieron eventos desde Last-Event-ID"}}); err != nil {
+			return
+		}
+	}
+	for _, e := range missed {
+		if err := writeSaleEvent(c, e); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	// mantiene abiertas las conexiones ociosas (y los proxies intermedios)
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// se quedó atrás; el cliente se reconecta con Last-Event-ID
+				return
+			}
+			if err := writeSaleEvent(c, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeSaleEvent writes e as a Server-Sent Event.
+func writeSaleEvent(c *gin.Context, e sale.Event) error {
+	return sse.Encode(c.Writer, sse.Event{
+		Id:    strconv.FormatInt(e.Seq, 10),
+		Event: string(e.Type),
+		Data:  e,
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sales-api/internal/sale"
+	"sales-api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// webhookRequest is the payload of POST /webhooks.
+type webhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// sin eventos el webhook recibe todos
+	Events []sale.EventType `json:"events"`
+}
+
+// handleRegisterWebhook handles POST /webhooks. The response carries the
+// secret deliveries are signed with; it is not shown again.
+func (h *handler) handleRegisterWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, err := h.webhooks.Register(req.URL, req.Events)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, webhook.ErrInvalidWebhook) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.log(c).Info("webhook registered", zap.String("id", w.ID), zap.String("url", w.URL))
+	c.JSON(http.StatusCreated, w)
+}
+
+// handleReadWebhook handles GET /webhooks/:id.
+func (h *handler) handleReadWebhook(c *gin.Context) {
+	w, err := h.webhooks.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// handleWebhookDeliveries handles GET /webhooks/:id/deliveries, listing
+// every delivery to the webhook, oldest first.
+func (h *handler) handleWebhookDeliveries(c *gin.Context) {
+	id := c.Param("id")
+	deliveries, err := h.webhooks.Deliveries(id)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook_id": id, "results": deliveries})
+}
+
+// handleDeadLetters handles GET /webhooks/dead-letters, listing the
+// deliveries of every webhook that exhausted their attempts.
+func (h *handler) handleDeadLetters(c *gin.Context) {
+	dead, err := h.webhooks.DeadLetters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": dead})
+}
+
+// webhookErrorStatus maps an error reading a webhook to a status code.
+func webhookErrorStatus(err error) int {
+	if errors.Is(err, webhook.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,190 @@
+// Package config loads the settings of the sales service from an optional
+// YAML or TOML file and from environment variables, and validates them.
+package config
+
+import (
+	"net"
+	"net/url"
+	"time"
+
+	"platform/settings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrInvalidConfig is returned when a setting is unknown, malformed or
+// out of range.
+var ErrInvalidConfig = settings.ErrInvalid
+
+// EnvPrefix prefixes the environment variable of every setting: the key in
+// upper case with dots replaced by underscores, e.g. SALES_USERS_URL for
+// users.url.
+const EnvPrefix = "SALES_"
+
+// Config holds every setting of the sales service.
+type Config struct {
+	// Addr is the address the HTTP server listens on (addr).
+	Addr string
+	// Mode is the Gin mode: debug, release or test (mode).
+	Mode    string
+	Users   UsersConfig
+	Storage StorageConfig
+	Log     LogConfig
+	// RatesPath is a JSON file with exchange rates; empty disables
+	// conversions (rates).
+	RatesPath string
+	// IdempotencyWindow is how long POST /sales responses are replayed
+	// for a repeated Idempotency-Key (idempotency_window).
+	IdempotencyWindow time.Duration
+	// AdminToken enables the admin endpoints; empty disables them
+	// (admin_token).
+	AdminToken string
+	// StreamHeartbeat is how often GET /sales/stream writes a heartbeat
+	// (stream_heartbeat).
+	StreamHeartbeat time.Duration
+	Shutdown        ShutdownConfig
+	Health          HealthConfig
+}
+
+// UsersConfig locates the users service.
+type UsersConfig struct {
+	URL     string        // users.url
+	Timeout time.Duration // users.timeout, per attempt
+	Retries int           // users.retries
+}
+
+// StorageConfig selects the sale storage.
+type StorageConfig struct {
+	Kind string // storage.kind: memory or sqlite
+	DB   string // storage.db: SQLite file, for sqlite
+}
+
+// LogConfig configures the zap logger.
+type LogConfig struct {
+	Level  string // log.level: debug, info, warn or error
+	Format string // log.format: json or console
+}
+
+// ShutdownConfig bounds the graceful shutdown.
+type ShutdownConfig struct {
+	Timeout time.Duration // shutdown.timeout: drain, workers and hooks
+	Delay   time.Duration // shutdown.delay: not-ready before draining
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	Timeout time.Duration // health.timeout, per check
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Addr: ":8081",
+		Mode: gin.DebugMode,
+		Users: UsersConfig{
+			URL:     "http://localhost:8080",
+			Timeout: 2 * time.Second,
+			Retries: 2,
+		},
+		Storage: StorageConfig{
+			Kind: "memory",
+			DB:   "sales.db",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		IdempotencyWindow: 24 * time.Hour,
+		StreamHeartbeat:   15 * time.Second,
+		Shutdown: ShutdownConfig{
+			Timeout: 15 * time.Second,
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+// Load returns the configuration, validated. Each setting is taken from,
+// in increasing order of precedence: Default, the file at path (if path is
+// not empty), its environment variable (see EnvVar) and overrides, which
+// maps keys such as "storage.kind" to values, e.g. from flags.
+//
+// The file is YAML (.yaml, .yml) or TOML (.toml); sections nest keys, so
+// storage.kind is the kind key of the storage section.
+func Load(path string, overrides map[string]string) (*Config, error) {
+	cfg := Default()
+	if err := settings.Load(cfg.settings(), EnvPrefix, path, overrides); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// EnvVar returns the environment variable that sets key.
+func EnvVar(key string) string {
+	return settings.EnvVar(EnvPrefix, key)
+}
+
+// settings lists every setting by key, with the function that parses a
+// value into c.
+func (c *Config) settings() []settings.Setting {
+	return []settings.Setting{
+		{Key: "addr", Set: settings.String(&c.Addr)},
+		{Key: "mode", Set: settings.String(&c.Mode)},
+		{Key: "users.url", Set: settings.String(&c.Users.URL)},
+		{Key: "users.timeout", Set: settings.Duration(&c.Users.Timeout)},
+		{Key: "users.retries", Set: settings.Int(&c.Users.Retries)},
+		{Key: "storage.kind", Set: settings.String(&c.Storage.Kind)},
+		{Key: "storage.db", Set: settings.String(&c.Storage.DB)},
+		{Key: "log.level", Set: settings.String(&c.Log.Level)},
+		{Key: "log.format", Set: settings.String(&c.Log.Format)},
+		{Key: "rates", Set: settings.String(&c.RatesPath)},
+		{Key: "idempotency_window", Set: settings.Duration(&c.IdempotencyWindow)},
+		{Key: "admin_token", Set: settings.String(&c.AdminToken)},
+		{Key: "stream_heartbeat", Set: settings.Duration(&c.StreamHeartbeat)},
+		{Key: "shutdown.timeout", Set: settings.Duration(&c.Shutdown.Timeout)},
+		{Key: "shutdown.delay", Set: settings.Duration(&c.Shutdown.Delay)},
+		{Key: "health.timeout", Set: settings.Duration(&c.Health.Timeout)},
+	}
+}
+
+// Validate checks every setting and reports all the problems found,
+// wrapping ErrInvalidConfig.
+func (c *Config) Validate() error {
+	var p settings.Problems
+
+	_, _, err := net.SplitHostPort(c.Addr)
+	p.Check(err == nil, "addr %q is not host:port", c.Addr)
+	p.Check(c.Mode == gin.DebugMode || c.Mode == gin.ReleaseMode || c.Mode == gin.TestMode,
+		"mode %q must be debug, release or test", c.Mode)
+
+	u, err := url.Parse(c.Users.URL)
+	p.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"users.url %q must be an absolute http or https url", c.Users.URL)
+	p.Check(c.Users.Timeout > 0, "users.timeout must be positive")
+	p.Check(c.Users.Retries >= 0, "users.retries cannot be negative")
+
+	switch c.Storage.Kind {
+	case "memory":
+	case "sqlite":
+		p.Check(c.Storage.DB != "", "storage.db is required with storage.kind=sqlite")
+	default:
+		p.Check(false, "storage.kind %q must be memory or sqlite", c.Storage.Kind)
+	}
+
+	_, err = zapcore.ParseLevel(c.Log.Level)
+	p.Check(err == nil, "log.level %q is unknown", c.Log.Level)
+	p.Check(c.Log.Format == "json" || c.Log.Format == "console", "log.format %q must be json or console", c.Log.Format)
+
+	p.Check(c.IdempotencyWindow > 0, "idempotency_window must be positive")
+	p.Check(c.StreamHeartbeat > 0, "stream_heartbeat must be positive")
+	p.Check(c.Shutdown.Timeout > 0, "shutdown.timeout must be positive")
+	p.Check(c.Shutdown.Delay >= 0, "shutdown.delay cannot be negative")
+	p.Check(c.Health.Timeout > 0, "health.timeout must be positive")
+
+	return p.Err()
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), *cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "sales.yaml", `
+addr: ":9000"
+idempotency_window: 1h
+users:
+  url: http://users:8080
+  retries: 5
+storage:
+  kind: sqlite
+  db: /data/sales.db
+`)
+	t.Setenv("SALES_USERS_URL", "http://users.internal:8080")
+	t.Setenv("SALES_STORAGE_DB", "/tmp/env.db")
+
+	cfg, err := Load(path, map[string]string{"storage.db": "/tmp/flag.db"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Addr)
+	assert.Equal(t, time.Hour, cfg.IdempotencyWindow)
+	assert.Equal(t, 5, cfg.Users.Retries)
+	assert.Equal(t, "sqlite", cfg.Storage.Kind)
+	// el entorno pisa al archivo y los parámetros al entorno
+	assert.Equal(t, "http://users.internal:8080", cfg.Users.URL)
+	assert.Equal(t, "/tmp/flag.db", cfg.Storage.DB)
+	// lo que nadie define queda por defecto
+	assert.Equal(t, Default().Users.Timeout, cfg.Users.Timeout)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "sales.toml", `
+admin_token = "secreto"
+stream_heartbeat = "5s"
+
+[log]
+level = "debug"
+format = "console"
+`)
+	cfg, err := Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secreto", cfg.AdminToken)
+	assert.Equal(t, 5*time.Second, cfg.StreamHeartbeat)
+	assert.Equal(t, LogConfig{Level: "debug", Format: "console"}, cfg.Log)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		overrides map[string]string
+		contains  []string
+	}{
+		{
+			name:     "clave desconocida en el archivo",
+			file:     "port: 8081\n",
+			contains: []string{`unknown key "port"`},
+		},
+		{
+			name:     "duración mal escrita",
+			env:      map[string]string{"SALES_USERS_TIMEOUT": "2 segundos"},
+			contains: []string{"users.timeout"},
+		},
+		{
+			name:      "informa todos los errores juntos",
+			overrides: map[string]string{"storage.kind": "postgres", "users.url": "users:8080", "addr": "8081"},
+			contains:  []string{"storage.kind", "users.url", "addr"},
+		},
+		{
+			name:      "sqlite sin archivo",
+			overrides: map[string]string{"storage.kind": "sqlite", "storage.db": ""},
+			contains:  []string{"storage.db"},
+		},
+		{
+			name:      "nivel de log desconocido",
+			overrides: map[string]string{"log.level": "verbose"},
+			contains:  []string{"log.level"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, "sales.yaml", tt.file)
+			}
+
+			_, err := Load(path, tt.overrides)
+			require.ErrorIs(t, err, ErrInvalidConfig)
+			for _, s := range tt.contains {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+
+	t.Run("formato no soportado", func(t *testing.T) {
+		_, err := Load(writeFile(t, "sales.json", "{}"), nil)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestEnvVar(t *testing.T) {
+	assert.Equal(t, "SALES_USERS_URL", EnvVar("users.url"))
+	assert.Equal(t, "SALES_ADMIN_TOKEN", EnvVar("admin_token"))
+}
@@ -0,0 +1,316 @@
+// Package metrics keeps counters and histograms and exposes them in the
+// Prometheus text exposition format (version 0.0.4).
+//
+// It covers what the service needs without pulling in the Prometheus
+// client: labelled counters and histograms, plus counters and gauges read
+// from a function on every scrape. There are no summaries.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets, in seconds, suited to
+// HTTP request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is a metric family the registry can write.
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and writes them out, sorted by name.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds c, panicking if its name is already taken, as that is a
+// programming error.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.collectors {
+		if other.name() == c.name() {
+			panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+		}
+	}
+	r.collectors = append(r.collectors, c)
+	slices.SortFunc(r.collectors, func(a, b collector) int {
+		return strings.Compare(a.name(), b.name())
+	})
+}
+
+// NewCounterVec registers a counter family with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{family: newFamily(name, help, labels)}
+	r.register(v)
+	return v
+}
+
+// NewHistogramVec registers a histogram family with the given upper
+// bounds, in increasing order, and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{family: newFamily(name, help, labels), buckets: buckets}
+	r.register(v)
+	return v
+}
+
+// NewCounterFunc registers a counter without labels whose value is read
+// from fn on every scrape, for counts kept elsewhere. fn must be safe for
+// concurrent use and never decrease.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{family: newFamily(name, help, nil), typ: "counter", fn: fn})
+}
+
+// NewGaugeFunc registers a gauge without labels whose value is read from
+// fn on every scrape. fn must be safe for concurrent use.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{family: newFamily(name, help, nil), typ: "gauge", fn: fn})
+}
+
+// Write writes every metric in the exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics, e.g. on GET /metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.Write(w)
+}
+
+// family holds the series of a metric, keyed by their label values.
+type family struct {
+	metric string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string][]string // clave → valores de las etiquetas
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{metric: name, help: help, labels: labels, series: map[string][]string{}}
+}
+
+func (f *family) name() string {
+	return f.metric
+}
+
+// key returns the series key of the label values, registering them. mu
+// must be held.
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metric, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := f.series[key]; !ok {
+		f.series[key] = append([]string(nil), values...)
+	}
+	return key
+}
+
+// sortedKeys returns the series keys in a stable order. mu must be held.
+func (f *family) sortedKeys() []string {
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// header writes the HELP and TYPE lines.
+func (f *family) header(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metric, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metric, typ)
+}
+
+// labelPairs formats label values as {a="x",b="y"}, with extra pairs
+// appended, or "" if there are none.
+func (f *family) labelPairs(values []string, extra ...string) string {
+	var pairs []string
+	for i, v := range values {
+		pairs = append(pairs, f.labels[i]+`="`+escapeLabel(v)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a family of counters partitioned by labels.
+type CounterVec struct {
+	family
+	values map[string]float64
+}
+
+// Add adds delta, which must not be negative, to the counter with the
+// given label values.
+func (v *CounterVec) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", v.metric))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.values == nil {
+		v.values = map[string]float64{}
+	}
+	v.values[v.key(labels)] += delta
+}
+
+// Inc adds one to the counter with the given label values.
+func (v *CounterVec) Inc(labels ...string) {
+	v.Add(1, labels...)
+}
+
+// Value returns the counter with the given label values.
+func (v *CounterVec) Value(labels ...string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.values[strings.Join(labels, "\xff")]
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.header(w, "counter")
+	for _, k := range v.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", v.metric, v.labelPairs(v.series[k]), formatFloat(v.values[k]))
+	}
+}
+
+// HistogramVec is a family of histograms partitioned by labels.
+type HistogramVec struct {
+	family
+	buckets []float64
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // por bucket, no acumulado
+	count  uint64
+	sum    float64
+}
+
+// Observe records value in the histogram with the given label values.
+func (v *HistogramVec) Observe(value float64, labels ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.values == nil {
+		v.values = map[string]*histogram{}
+	}
+	key := v.key(labels)
+	h, ok := v.values[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(v.buckets))}
+		v.values[key] = h
+	}
+	if i, _ := slices.BinarySearch(v.buckets, value); i < len(v.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+// Count returns how many values the histogram with the given label values
+// recorded.
+func (v *HistogramVec) Count(labels ...string) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if h, ok := v.values[strings.Join(labels, "\xff")]; ok {
+		return h.count
+	}
+	return 0
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.header(w, "histogram")
+	for _, k := range v.sortedKeys() {
+		values, h := v.series[k], v.values[k]
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.metric, v.labelPairs(values, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.metric, v.labelPairs(values, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.metric, v.labelPairs(values), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metric, v.labelPairs(values), h.count)
+	}
+}
+
+// valueFunc is a metric without labels read from a function.
+type valueFunc struct {
+	family
+	typ string
+	fn  func() float64
+}
+
+func (v *valueFunc) write(w *bufio.Writer) {
+	v.header(w, v.typ)
+	fmt.Fprintf(w, "%s %s\n", v.metric, formatFloat(v.fn()))
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
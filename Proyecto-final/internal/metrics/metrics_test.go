@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("http_requests_total", "Total HTTP requests.", "method", "status")
+	latency := r.NewHistogramVec("http_request_duration_seconds", "Request latency.", []float64{0.1, 0.5}, "method")
+	amount := r.NewCounterVec("sales_amount_total", "Sold amount.\nBy currency.", "currency")
+
+	requests.Inc("GET", "200")
+	requests.Inc("GET", "200")
+	requests.Inc("POST", "500")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.1, "GET")
+	latency.Observe(2, "GET")
+	amount.Add(10.5, `A"R\S`)
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, `# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{method="GET",le="0.1"} 2
+http_request_duration_seconds_bucket{method="GET",le="0.5"} 2
+http_request_duration_seconds_bucket{method="GET",le="+Inf"} 3
+http_request_duration_seconds_sum{method="GET"} 2.15
+http_request_duration_seconds_count{method="GET"} 3
+# HELP http_requests_total Total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",status="200"} 2
+http_requests_total{method="POST",status="500"} 1
+# HELP sales_amount_total Sold amount.\nBy currency.
+# TYPE sales_amount_total counter
+sales_amount_total{currency="A\"R\\S"} 10.5
+`, out.String())
+
+	assert.Equal(t, 2.0, requests.Value("GET", "200"))
+	assert.Equal(t, 0.0, requests.Value("PUT", "200"))
+	assert.Equal(t, uint64(3), latency.Count("GET"))
+}
+
+func TestRegistry_Funcs(t *testing.T) {
+	r := NewRegistry()
+	hits := 3.0
+	r.NewCounterFunc("cache_hits_total", "Cache hits.", func() float64 { return hits })
+	r.NewGaugeFunc("cache_entries", "Cached entries.", func() float64 { return 2 })
+
+	hits = 5 // se lee al escribir, no al registrar
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, `# HELP cache_entries Cached entries.
+# TYPE cache_entries gauge
+cache_entries 2
+# HELP cache_hits_total Cache hits.
+# TYPE cache_hits_total counter
+cache_hits_total 5
+`, out.String())
+
+	assert.Panics(t, func() { r.NewGaugeFunc("cache_entries", "again", nil) }, "nombre repetido")
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("sales_created_total", "Sales created.", "currency")
+
+	assert.Panics(t, func() { r.NewCounterVec("sales_created_total", "again") }, "nombre repetido")
+	assert.Panics(t, func() { c.Inc() }, "faltan etiquetas")
+	assert.Panics(t, func() { c.Add(-1, "ARS") }, "un contador no decrece")
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("up_total", "Up.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "\nup_total 1\n")
+}
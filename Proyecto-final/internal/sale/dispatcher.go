@@ -0,0 +1,191 @@
+package sale
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default dispatcher settings.
+const (
+	DefaultDispatchInterval   = time.Second
+	DefaultDispatchBatch      = 100
+	DefaultDispatchMaxBackoff = time.Minute
+)
+
+// Subscriber receives domain events. Returning an error makes the
+// dispatcher deliver the event again later; since delivery is
+// at-least-once, subscribers must tolerate duplicates (see Event.ID).
+type Subscriber func(ctx context.Context, e Event) error
+
+// Dispatcher delivers the events of a Storage's outbox to its subscribers.
+//
+// Events are delivered in Seq order. An event is marked as dispatched once
+// every subscriber has accepted it; if one fails, dispatching pauses with
+// exponential backoff and resumes from that event, without delivering it
+// again to the subscribers that already accepted it. That memory does not
+// survive a restart, so subscribers may see an event more than once.
+//
+// Delivery is head-of-line: while one subscriber keeps failing, no later
+// event reaches any subscriber, with retries up to DefaultDispatchMaxBackoff
+// apart. Subscribers that can fail for long, such as remote endpoints,
+// should store the event durably and return, retrying on their own, as
+// webhook.Service does.
+type Dispatcher struct {
+	storage    Storage
+	interval   time.Duration
+	batch      int
+	maxBackoff time.Duration
+	onError    func(e Event, subscriber string, err error)
+
+	mu          sync.RWMutex
+	subscribers []subscription
+
+	// delivered remembers, for the event being retried, which
+	// subscribers already accepted it. Only used by Dispatch.
+	delivered map[string]bool
+	retrying  int64
+
+	wake chan struct{}
+}
+
+type subscription struct {
+	name string
+	fn   Subscriber
+}
+
+// DispatcherOption configures optional Dispatcher behaviour.
+type DispatcherOption func(*Dispatcher)
+
+// WithDispatchInterval sets how often the outbox is polled, and the
+// initial backoff after a failed delivery.
+func WithDispatchInterval(d time.Duration) DispatcherOption {
+	return func(dp *Dispatcher) {
+		dp.interval = d
+	}
+}
+
+// WithDispatchErrorHandler sets a function called for every failed
+// delivery, e.g. to log it.
+func WithDispatchErrorHandler(fn func(e Event, subscriber string, err error)) DispatcherOption {
+	return func(dp *Dispatcher) {
+		dp.onError = fn
+	}
+}
+
+// NewDispatcher creates a dispatcher for the outbox of storage.
+// Call Run to start delivering.
+func NewDispatcher(storage Storage, opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		storage:    storage,
+		interval:   DefaultDispatchInterval,
+		batch:      DefaultDispatchBatch,
+		maxBackoff: DefaultDispatchMaxBackoff,
+		onError:    func(Event, string, error) {},
+		delivered:  map[string]bool{},
+		wake:       make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Subscribe registers fn to receive every event dispatched from now on.
+// name identifies the subscriber in error reports and must be unique:
+// Subscribe panics if it is taken, as that is a programming error.
+// It is safe to call while Run is running.
+func (d *Dispatcher) Subscribe(name string, fn Subscriber) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, sub := range d.subscribers {
+		if sub.name == name {
+			panic(fmt.Sprintf("sale: subscriber %q registered twice", name))
+		}
+	}
+	d.subscribers = append(d.subscribers, subscription{name: name, fn: fn})
+}
+
+// Notify wakes the dispatcher up so new events are delivered without
+// waiting for the next poll. It never blocks.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run dispatches events until ctx is cancelled, polling the outbox every
+// interval and whenever Notify is called. It returns ctx.Err().
+func (d *Dispatcher) Run(ctx context.Context) error {
+	backoff := d.interval
+	for {
+		wait := d.interval
+		if _, err := d.Dispatch(ctx); err != nil {
+			wait = backoff
+			backoff = min(backoff*2, d.maxBackoff)
+		} else {
+			backoff = d.interval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-d.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Dispatch makes one pass over the pending outbox and returns how many
+// events were dispatched. It stops at the first event a subscriber fails
+// to accept, returning that error. Dispatch must not be called
+// concurrently with itself or Run.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	d.mu.RLock()
+	subscribers := append([]subscription(nil), d.subscribers...)
+	d.mu.RUnlock()
+
+	dispatched := 0
+	for {
+		events, err := d.storage.PendingEvents(d.batch)
+		if err != nil || len(events) == 0 {
+			return dispatched, err
+		}
+
+		for _, e := range events {
+			if err := d.deliver(ctx, e, subscribers); err != nil {
+				return dispatched, err
+			}
+			if err := d.storage.MarkDispatched(e.Seq); err != nil {
+				return dispatched, err
+			}
+			dispatched++
+		}
+	}
+}
+
+// deliver hands e to every subscriber that has not accepted it yet.
+func (d *Dispatcher) deliver(ctx context.Context, e Event, subscribers []subscription) error {
+	if d.retrying != e.Seq {
+		d.retrying = e.Seq
+		clear(d.delivered)
+	}
+
+	for _, sub := range subscribers {
+		if d.delivered[sub.name] {
+			continue
+		}
+		if err := sub.fn(ctx, e); err != nil {
+			d.onError(e, sub.name, err)
+			return err
+		}
+		d.delivered[sub.name] = true
+	}
+	return nil
+}
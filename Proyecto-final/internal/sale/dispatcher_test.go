@@ -0,0 +1,154 @@
+package sale
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_EmitsEvents(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+	svc := NewService(s)
+
+	approved := &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(approved))
+	_, err := svc.Update(approved.ID, &UpdateFields{Estado: StateApproved, Actor: "backoffice"})
+	require.NoError(t, err)
+
+	rejected := &Sale{UserID: "u2", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(rejected))
+	_, err = svc.Update(rejected.ID, &UpdateFields{Estado: StateRejected})
+	require.NoError(t, err)
+
+	// una transición rechazada no deja eventos
+	_, err = svc.Update(rejected.ID, &UpdateFields{Estado: StateApproved})
+	require.Error(t, err)
+
+	events, err := s.PendingEvents(10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+		if i > 0 {
+			assert.Greater(t, e.Seq, events[i-1].Seq)
+		}
+	}
+	assert.Equal(t, []EventType{SaleCreated, SaleApproved, SaleCreated, SaleRejected}, types)
+	assert.Equal(t, "backoffice", events[1].Actor)
+	assert.Equal(t, StateApproved, events[1].Sale.Estado)
+	assert.Equal(t, NewMoney(100, DefaultCurrency), events[1].Sale.Amount)
+
+	require.NoError(t, s.MarkDispatched(events[0].Seq, events[1].Seq))
+	events, err = s.PendingEvents(10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestService_ImportEmitsStateEvents(t *testing.T) {
+	s := NewLocalStorage()
+	svc := NewService(s)
+
+	require.NoError(t, svc.Import(&Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency), Estado: StateApproved}))
+	require.NoError(t, svc.ImportAll([]*Sale{
+		{UserID: "u2", Amount: NewMoney(100, DefaultCurrency)},
+		{UserID: "u3", Amount: NewMoney(100, DefaultCurrency), Estado: StateRejected},
+	}))
+
+	events, err := s.PendingEvents(10)
+	require.NoError(t, err)
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	// una venta importada ya aprobada o rechazada se anuncia como tal
+	assert.Equal(t, []EventType{SaleCreated, SaleApproved, SaleCreated, SaleCreated, SaleRejected}, types)
+	assert.Equal(t, events[0].SaleID, events[1].SaleID)
+	assert.Equal(t, events[3].SaleID, events[4].SaleID)
+}
+
+func TestDispatcher_AtLeastOnce(t *testing.T) {
+	storage := NewLocalStorage()
+	svc := NewService(storage)
+	for range 3 {
+		require.NoError(t, svc.Create(&Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}))
+	}
+
+	var failures []string
+	d := NewDispatcher(storage, WithDispatchErrorHandler(func(e Event, subscriber string, err error) {
+		failures = append(failures, subscriber)
+	}))
+
+	var audit, flaky []int64
+	d.Subscribe("audit", func(ctx context.Context, e Event) error {
+		audit = append(audit, e.Seq)
+		return nil
+	})
+	fail := true
+	d.Subscribe("flaky", func(ctx context.Context, e Event) error {
+		if e.Seq == 2 && fail {
+			fail = false
+			return errors.New("caído")
+		}
+		flaky = append(flaky, e.Seq)
+		return nil
+	})
+
+	n, err := d.Dispatch(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"flaky"}, failures)
+
+	// el reintento sigue desde el evento fallido, sin repetírselo a audit
+	n, err = d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int64{1, 2, 3}, audit)
+	assert.Equal(t, []int64{1, 2, 3}, flaky)
+
+	pending, err := storage.PendingEvents(10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestDispatcher_SubscribeTwice(t *testing.T) {
+	d := NewDispatcher(NewLocalStorage())
+	d.Subscribe("webhooks", func(context.Context, Event) error { return nil })
+	assert.Panics(t, func() {
+		d.Subscribe("webhooks", func(context.Context, Event) error { return nil })
+	})
+}
+
+func TestDispatcher_RunNotified(t *testing.T) {
+	storage := NewLocalStorage()
+	d := NewDispatcher(storage, WithDispatchInterval(time.Hour))
+	svc := NewService(storage, WithDispatcher(d))
+
+	got := make(chan Event, 1)
+	d.Subscribe("test", func(ctx context.Context, e Event) error {
+		got <- e
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	s := &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(s))
+
+	select {
+	case e := <-got:
+		assert.Equal(t, s.ID, e.SaleID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("el evento no se entregó")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
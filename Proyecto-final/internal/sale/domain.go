@@ -1,17 +1,86 @@
 package sale
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Sale struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"` // antes: user_id
 	Estado    string    `json:"estado"`  // antes: estado
-	Amount    float32   `json:"amount"`  // antes: amount
+	Amount    Money     `json:"amount"`  // antes: amount
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Version   int       `json:"version"`
+	// DeletedAt is set when the sale is soft-deleted: it is hidden from
+	// listings but kept, with its history, for audit.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// MarshalJSON keeps "amount" as a decimal number for existing clients and
+// adds the exact "amount_minor" and its "currency".
+func (s Sale) MarshalJSON() ([]byte, error) {
+	type alias Sale
+	return json.Marshal(struct {
+		alias
+		AmountMinor int64  `json:"amount_minor"`
+		Currency    string `json:"currency"`
+	}{alias(s), s.Amount.Minor, s.Amount.Currency})
+}
+
+// UnmarshalJSON accepts the output of MarshalJSON. When both are present,
+// "amount_minor" wins over the decimal "amount".
+func (s *Sale) UnmarshalJSON(data []byte) error {
+	type alias Sale
+	aux := struct {
+		*alias
+		Amount      json.Number `json:"amount"`
+		AmountMinor *int64      `json:"amount_minor"`
+		Currency    string      `json:"currency"`
+	}{alias: (*alias)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	currency := aux.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	switch {
+	case aux.AmountMinor != nil:
+		s.Amount = NewMoney(*aux.AmountMinor, currency)
+	case aux.Amount != "":
+		amount, err := ParseMoney(aux.Amount.String(), currency)
+		if err != nil {
+			return err
+		}
+		s.Amount = amount
+	}
+	return nil
 }
 
 type UpdateFields struct {
 	Estado string `json:"estado"` // antes: estado
+	// Version, when set, is the version the client last read; the update is
+	// rejected with ErrVersionConflict if the sale has changed since.
+	Version *int `json:"version"`
+	// Actor identifies who requested the change; it is recorded in the
+	// sale history and never read from the request body.
+	Actor string `json:"-"`
+}
+
+// HistoryDeleted is the To of the StateChange recorded when a sale is
+// soft-deleted. It is not a state: the deleted sale keeps its Estado.
+const HistoryDeleted = "deleted"
+
+// StateChange is an immutable audit record of a sale moving between states.
+// A soft delete is recorded as a change to HistoryDeleted.
+type StateChange struct {
+	SaleID  string    `json:"sale_id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	At      time.Time `json:"at"`
+	Actor   string    `json:"actor"`
+	Version int       `json:"version"` // versión que alcanzó la venta con este cambio
 }
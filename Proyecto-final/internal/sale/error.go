@@ -6,4 +6,15 @@ var (
 	ErrSaleNotFound       = errors.New("sale not found")
 	ErrInvalidStateChange = errors.New("transición de estado no permitida")
 	ErrInvalidNewState    = errors.New("estado no válido para cambio")
+	ErrInvalidStartState  = errors.New("estado inicial no permitido")
+	ErrTransitionRejected = errors.New("transición rechazada")
+	ErrInvalidPage        = errors.New("paginación inválida")
+	ErrInvalidFilter      = errors.New("filtro inválido")
+	ErrInvalidGroupBy     = errors.New("agrupamiento inválido")
+	ErrInvalidAmount      = errors.New("monto inválido")
+	ErrCurrencyMismatch   = errors.New("no se pueden combinar montos de distintas monedas")
+	ErrInvalidCurrency    = errors.New("moneda inválida")
+	ErrRateNotFound       = errors.New("no hay cotización para la conversión")
+	ErrNoRateProvider     = errors.New("la conversión de monedas no está configurada")
+	ErrAmountOverflow     = errors.New("el monto excede el rango admitido")
 )
@@ -0,0 +1,70 @@
+package sale
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies the kind of a domain event.
+type EventType string
+
+// Domain events emitted by Service.
+const (
+	// SaleCreated is emitted for every new sale, whatever state it starts in.
+	SaleCreated EventType = "SaleCreated"
+	// SaleApproved is emitted when a sale moves to StateApproved, or after
+	// SaleCreated for a sale imported already approved.
+	SaleApproved EventType = "SaleApproved"
+	// SaleRejected is emitted when a sale moves to StateRejected, or after
+	// SaleCreated for a sale imported already rejected.
+	SaleRejected EventType = "SaleRejected"
+)
+
+// Event is a domain event. It is written to the outbox in the same atomic
+// write as the change it describes, so an event exists if and only if the
+// change was stored.
+type Event struct {
+	// ID is unique per event; subscribers use it to discard duplicates,
+	// since delivery is at-least-once.
+	ID string `json:"id"`
+	// Seq orders the events of the outbox. It is assigned by the storage.
+	Seq    int64     `json:"seq"`
+	Type   EventType `json:"type"`
+	SaleID string    `json:"sale_id"`
+	At     time.Time `json:"occurred_at"`
+	// Actor is who made the change, for state changes.
+	Actor string `json:"actor,omitempty"`
+	// Sale is a snapshot of the sale right after the change.
+	Sale Sale `json:"sale"`
+}
+
+func newEvent(t EventType, sale Sale, actor string, at time.Time) Event {
+	return Event{
+		ID:     uuid.NewString(),
+		Type:   t,
+		SaleID: sale.ID,
+		At:     at,
+		Actor:  actor,
+		Sale:   sale,
+	}
+}
+
+// creationEvents returns the events creating sale emits: SaleCreated and,
+// for a sale that starts in a final state, the event of reaching it, so
+// subscribers see imported sales as approved or rejected.
+func creationEvents(sale Sale, at time.Time) []Event {
+	return append([]Event{newEvent(SaleCreated, sale, "", at)}, transitionEvents(sale, sale.Estado, "", at)...)
+}
+
+// transitionEvents returns the events a move to state to emits.
+func transitionEvents(sale Sale, to, actor string, at time.Time) []Event {
+	switch to {
+	case StateApproved:
+		return []Event{newEvent(SaleApproved, sale, actor, at)}
+	case StateRejected:
+		return []Event{newEvent(SaleRejected, sale, actor, at)}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package sale
+
+import (
+	"fmt"
+	"time"
+)
+
+// Filter selects which sales a listing returns.
+// Zero-valued fields do not filter, so the zero Filter matches every sale.
+type Filter struct {
+	UserID      string
+	Status      string
+	Currency    string
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // inclusive
+	MinAmount   *Money    // inclusive, compared by decimal value
+	MaxAmount   *Money    // inclusive, compared by decimal value
+	// IncludeDeleted also matches soft-deleted sales, which are skipped
+	// by default.
+	IncludeDeleted bool
+}
+
+// Validate checks that the ranges in the filter are not inverted.
+// Returns an error wrapping ErrInvalidFilter otherwise.
+func (f Filter) Validate() error {
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return fmt.Errorf("%w: created_from es posterior a created_to", ErrInvalidFilter)
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.Cmp(*f.MaxAmount) > 0 {
+		return fmt.Errorf("%w: min_amount es mayor que max_amount", ErrInvalidFilter)
+	}
+	return nil
+}
+
+// Match reports whether sale satisfies every condition of the filter.
+func (f Filter) Match(sale Sale) bool {
+	if sale.DeletedAt != nil && !f.IncludeDeleted {
+		return false
+	}
+	if f.UserID != "" && sale.UserID != f.UserID {
+		return false
+	}
+	if f.Status != "" && sale.Estado != f.Status {
+		return false
+	}
+	if f.Currency != "" && sale.Amount.Currency != f.Currency {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && sale.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && sale.CreatedAt.After(f.CreatedTo) {
+		return false
+	}
+	if f.MinAmount != nil && sale.Amount.Cmp(*f.MinAmount) < 0 {
+		return false
+	}
+	if f.MaxAmount != nil && sale.Amount.Cmp(*f.MaxAmount) > 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package sale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_FindWithFilter(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+	for _, s := range []*Sale{
+		{UserID: "u1", Amount: NewMoney(5000, DefaultCurrency)},
+		{UserID: "u1", Amount: NewMoney(15000, DefaultCurrency), Estado: StateApproved},
+		{UserID: "u2", Amount: NewMoney(25000, DefaultCurrency)},
+	} {
+		require.NoError(t, svc.Import(s))
+	}
+
+	amount := func(units int64) *Money {
+		m := NewMoney(units*100, DefaultCurrency)
+		return &m
+	}
+	cases := map[string]struct {
+		filter Filter
+		want   int
+	}{
+		"sin filtros (back-office)": {Filter{}, 3},
+		"por usuario":               {Filter{UserID: "u1"}, 2},
+		"por estado":                {Filter{Status: StatePending}, 2},
+		"rango de montos":           {Filter{MinAmount: amount(100), MaxAmount: amount(250)}, 2},
+		"monto mínimo y usuario":    {Filter{UserID: "u1", MinAmount: amount(100)}, 1},
+		"desde el futuro":           {Filter{CreatedFrom: time.Now().Add(time.Hour)}, 0},
+		"hasta ahora":               {Filter{CreatedTo: time.Now()}, 3},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sales, err := svc.Find(tc.filter)
+			require.NoError(t, err)
+			assert.Len(t, sales, tc.want)
+		})
+	}
+
+	_, err := svc.Find(Filter{MinAmount: amount(10), MaxAmount: amount(1)})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = svc.Find(Filter{CreatedFrom: time.Now(), CreatedTo: time.Now().Add(-time.Hour)})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
@@ -0,0 +1,19 @@
+package sale
+
+// Metrics receives business measurements from Service, after each write
+// is stored. Implementations must be safe for concurrent use and should
+// not block.
+type Metrics interface {
+	// SaleCreated is called for every new sale, in whatever state it
+	// starts.
+	SaleCreated(sale Sale)
+	// SaleStateChanged is called when a sale moves from one state to
+	// another; sale is already in the new state.
+	SaleStateChanged(sale Sale, from string)
+}
+
+// noMetrics discards every measurement.
+type noMetrics struct{}
+
+func (noMetrics) SaleCreated(Sale)              {}
+func (noMetrics) SaleStateChanged(Sale, string) {}
@@ -0,0 +1,55 @@
+package sale
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics keeps what Service reports, as "created:<estado>" or
+// "<from>-><to>".
+type recordingMetrics struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *recordingMetrics) SaleCreated(sale Sale) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, "created:"+sale.Estado)
+}
+
+func (m *recordingMetrics) SaleStateChanged(sale Sale, from string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, from+"->"+sale.Estado)
+}
+
+func TestService_Metrics(t *testing.T) {
+	m := &recordingMetrics{}
+	svc := NewService(NewLocalStorage(), WithMetrics(m))
+
+	s := &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(s))
+	_, err := svc.Update(s.ID, &UpdateFields{Estado: StateApproved})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.ImportAll([]*Sale{
+		{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)},
+		{UserID: "u2", Amount: NewMoney(100, DefaultCurrency), Estado: StateRejected},
+	}))
+
+	// lo que falla no se mide
+	assert.Error(t, svc.Create(&Sale{UserID: "u1"}))
+	_, err = svc.Update(s.ID, &UpdateFields{Estado: StateRejected})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{
+		"created:" + StatePending,
+		StatePending + "->" + StateApproved,
+		"created:" + StatePending,
+		"created:" + StateRejected,
+	}, m.events)
+}
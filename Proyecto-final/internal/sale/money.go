@@ -0,0 +1,170 @@
+package sale
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of sales created without one.
+const DefaultCurrency = "ARS"
+
+// Money is a fixed-point monetary amount: Minor is the amount expressed in
+// minor units (e.g. cents) of Currency, so 150.50 ARS is {15050, "ARS"}.
+// Unlike float32 it adds up without rounding errors.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// NewMoney returns an amount of minor units of currency.
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount such as "150", "150.5" or "150.50"
+// exactly, without going through floating point.
+// Returns an error wrapping ErrInvalidAmount if s is not a plain decimal
+// number or has more decimal places than the currency allows.
+func ParseMoney(s, currency string) (Money, error) {
+	raw := s
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q no es un número decimal", ErrInvalidAmount, raw)
+	}
+
+	// los ceros de más a la derecha no cambian el valor: 1.500 == 1.50
+	digits := minorDigits(currency)
+	if len(frac) > digits {
+		if strings.Trim(frac[digits:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %s admite hasta %d decimales", ErrInvalidAmount, currency, digits)
+		}
+		frac = frac[:digits]
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q está fuera de rango", ErrInvalidAmount, raw)
+	}
+	if neg {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// Add returns m + o.
+// Returns ErrCurrencyMismatch if both amounts are in different currencies,
+// or an error wrapping ErrAmountOverflow if the sum does not fit in Minor.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s y %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	minor, err := addMinor(m.Minor, o.Minor)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w (%s)", err, m.Currency)
+	}
+	return Money{Minor: minor, Currency: m.Currency}, nil
+}
+
+// addMinor returns a + b, or ErrAmountOverflow if it does not fit in an
+// int64.
+func addMinor(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrAmountOverflow
+	}
+	return a + b, nil
+}
+
+// Totals adds up the amounts of sales per currency.
+// Returns an error wrapping ErrAmountOverflow if a total does not fit in
+// Money.Minor.
+func Totals(sales []Sale) (map[string]Money, error) {
+	agg := MustNewAggregator(GroupByNone)
+	for _, s := range sales {
+		if err := agg.Add(s); err != nil {
+			return nil, err
+		}
+	}
+	return agg.Stats().Total.Sums(), nil
+}
+
+// Cmp compares the decimal values of m and o, ignoring their currencies:
+// -1 if m < o, 0 if equal, +1 if m > o.
+// Amounts whose currencies have different minor units are compared by
+// decimal value, so 5 JPY equals 5.00 USD.
+func (m Money) Cmp(o Money) int {
+	a, b := m.Minor, o.Minor
+	da, db := minorDigits(m.Currency), minorDigits(o.Currency)
+	if da != db {
+		// comparar en big.Int: escalar a la unidad más chica puede desbordar int64
+		x, y := big.NewInt(a), big.NewInt(b)
+		if da < db {
+			x.Mul(x, pow10(db-da))
+		} else {
+			y.Mul(y, pow10(da-db))
+		}
+		return x.Cmp(y)
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// String formats the amount as a plain decimal number with as many decimal
+// places as the currency's minor unit, e.g. "150.50" ARS or "150" JPY.
+func (m Money) String() string {
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	places := minorDigits(m.Currency)
+	if places == 0 {
+		return sign + digits
+	}
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	cut := len(digits) - places
+
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// MarshalJSON encodes the amount as an exact JSON number, e.g. 150.50, so
+// clients that read amounts as numbers keep working.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
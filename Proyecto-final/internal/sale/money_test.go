@@ -0,0 +1,96 @@
+package sale
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	valid := map[string]int64{
+		"150":    15000,
+		"150.5":  15050,
+		"150.50": 15050,
+		"0.1":    10,
+		"1.500":  150,
+		"-2.25":  -225,
+	}
+	for in, minor := range valid {
+		m, err := ParseMoney(in, DefaultCurrency)
+		require.NoError(t, err, in)
+		assert.Equal(t, minor, m.Minor, in)
+	}
+
+	for _, in := range []string{"", "abc", "1.", ".5", "1.234", "1e3", "99999999999999999999"} {
+		_, err := ParseMoney(in, DefaultCurrency)
+		assert.ErrorIs(t, err, ErrInvalidAmount, in)
+	}
+}
+
+func TestMoney_AddIsExact(t *testing.T) {
+	// 0.1 + 0.2 en float da 0.30000000000000004
+	a, _ := ParseMoney("0.1", DefaultCurrency)
+	b, _ := ParseMoney("0.2", DefaultCurrency)
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.Equal(t, "0.30", sum.String())
+
+	_, err = a.Add(NewMoney(1, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(math.MaxInt64, DefaultCurrency).Add(NewMoney(1, DefaultCurrency))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = NewMoney(math.MinInt64, DefaultCurrency).Add(NewMoney(-1, DefaultCurrency))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestSale_JSON(t *testing.T) {
+	raw, err := json.Marshal(Sale{ID: "s1", Amount: NewMoney(-5, "USD")})
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"amount":-0.05`)
+	assert.Contains(t, string(raw), `"amount_minor":-5`)
+	assert.Contains(t, string(raw), `"currency":"USD"`)
+
+	var s Sale
+	require.NoError(t, json.Unmarshal(raw, &s))
+	assert.Equal(t, NewMoney(-5, "USD"), s.Amount)
+
+	// clientes viejos: solo amount decimal, sin moneda
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"s2","amount":150.5}`), &s))
+	assert.Equal(t, NewMoney(15050, DefaultCurrency), s.Amount)
+}
+
+func TestMoney_CurrencyExponent(t *testing.T) {
+	jpy, err := ParseMoney("150", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), jpy.Minor)
+	assert.Equal(t, "150", jpy.String())
+
+	_, err = ParseMoney("150.5", "JPY")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	kwd, err := ParseMoney("1.5", "KWD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), kwd.Minor)
+	assert.Equal(t, "1.500", kwd.String())
+
+	// 5 JPY == 5.00 USD por valor decimal
+	assert.Equal(t, 0, NewMoney(5, "JPY").Cmp(NewMoney(500, "USD")))
+	assert.Equal(t, 1, NewMoney(1501, "KWD").Cmp(NewMoney(150, "USD")))
+}
+
+func TestTotals(t *testing.T) {
+	totals, err := Totals([]Sale{
+		{Amount: NewMoney(1000, "ARS")},
+		{Amount: NewMoney(250, "USD")},
+		{Amount: NewMoney(500, "ARS")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Money{
+		"ARS": NewMoney(1500, "ARS"),
+		"USD": NewMoney(250, "USD"),
+	}, totals)
+}
@@ -0,0 +1,166 @@
+package sale
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Fields a listing can be sorted by.
+const (
+	SortCreatedAt = "created_at"
+	SortAmount    = "amount"
+)
+
+// Page size limits.
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// Page controls the order and the window of a sales listing.
+//
+// Results are always ordered by Sort and then by ID, so the order is stable
+// between requests. Either Offset or Cursor may be used, not both.
+type Page struct {
+	Sort   string // SortCreatedAt (default) or SortAmount
+	Desc   bool
+	Limit  int    // 0 means DefaultPageLimit
+	Offset int    // number of results to skip
+	Cursor string // NextCursor of the previous page
+}
+
+// PageResult is one page of a sales listing.
+type PageResult struct {
+	Sales []Sale
+	// Total is the number of sales matching the filters, across all pages.
+	Total int
+	// NextCursor resumes the listing after the last sale of this page.
+	// It is empty on the last page.
+	NextCursor string
+	// Stats aggregates the sales matching the filters, across all pages,
+	// grouped by status.
+	Stats *Stats
+}
+
+// cursor is the decoded form of Page.Cursor: the sort it was issued for and
+// the sort key of the last sale returned.
+type cursor struct {
+	Sort      string `json:"s"`
+	Desc      bool   `json:"d"`
+	CreatedAt int64  `json:"t"`
+	Amount    int64  `json:"a"`
+	Currency  string `json:"c"`
+	ID        string `json:"i"`
+}
+
+// validate normalizes defaults and checks the page parameters.
+func (p *Page) validate() error {
+	if p.Sort == "" {
+		p.Sort = SortCreatedAt
+	}
+	if p.Sort != SortCreatedAt && p.Sort != SortAmount {
+		return fmt.Errorf("%w: no se puede ordenar por %q", ErrInvalidPage, p.Sort)
+	}
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return fmt.Errorf("%w: limit debe estar entre 1 y %d", ErrInvalidPage, MaxPageLimit)
+	}
+	if p.Offset < 0 {
+		return fmt.Errorf("%w: offset no puede ser negativo", ErrInvalidPage)
+	}
+	if p.Cursor != "" && p.Offset > 0 {
+		return fmt.Errorf("%w: cursor y offset son excluyentes", ErrInvalidPage)
+	}
+	return nil
+}
+
+// apply sorts sales in place and cuts out the requested page.
+func (p Page) apply(sales []Sale) (*PageResult, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(sales, func(a, b Sale) int {
+		return p.compare(a, b)
+	})
+
+	start := p.Offset
+	if p.Cursor != "" {
+		after, err := p.decodeCursor()
+		if err != nil {
+			return nil, err
+		}
+		start, _ = slices.BinarySearchFunc(sales, after, p.compare)
+		// el cursor apunta a la última venta devuelta: arrancar en la siguiente
+		if start < len(sales) && p.compare(sales[start], after) == 0 {
+			start++
+		}
+	}
+	start = min(start, len(sales))
+	end := min(start+p.Limit, len(sales))
+
+	result := &PageResult{
+		Sales: append([]Sale{}, sales[start:end]...),
+		Total: len(sales),
+	}
+	if end < len(sales) {
+		result.NextCursor = p.encodeCursor(sales[end-1])
+	}
+
+	return result, nil
+}
+
+// compare orders two sales by the page's sort field, breaking ties by ID.
+func (p Page) compare(a, b Sale) int {
+	var c int
+	switch p.Sort {
+	case SortAmount:
+		c = a.Amount.Cmp(b.Amount)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if p.Desc {
+		return -c
+	}
+	return c
+}
+
+func (p Page) encodeCursor(last Sale) string {
+	raw, _ := json.Marshal(cursor{
+		Sort:      p.Sort,
+		Desc:      p.Desc,
+		CreatedAt: last.CreatedAt.UnixNano(),
+		Amount:    last.Amount.Minor,
+		Currency:  last.Amount.Currency,
+		ID:        last.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor returns a sale carrying the cursor's sort key, suitable for
+// compare. The cursor must have been issued for the same ordering.
+func (p Page) decodeCursor() (Sale, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return Sale{}, fmt.Errorf("%w: cursor inválido", ErrInvalidPage)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Sale{}, fmt.Errorf("%w: cursor inválido", ErrInvalidPage)
+	}
+	if c.Sort != p.Sort || c.Desc != p.Desc {
+		return Sale{}, fmt.Errorf("%w: el cursor corresponde a otro orden", ErrInvalidPage)
+	}
+
+	return Sale{ID: c.ID, CreatedAt: time.Unix(0, c.CreatedAt), Amount: NewMoney(c.Amount, c.Currency)}, nil
+}
@@ -0,0 +1,81 @@
+package sale
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSales(n int) []Sale {
+	base := time.Now()
+	sales := make([]Sale, n)
+	for i := range sales {
+		sales[i] = Sale{
+			ID:        fmt.Sprintf("s%02d", i),
+			Amount:    NewMoney(int64(i%3), DefaultCurrency), // montos repetidos para forzar desempate por ID
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return sales
+}
+
+func TestPage_CursorWalksEverySaleOnce(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		sales := testSales(10)
+		page := Page{Sort: SortAmount, Desc: desc, Limit: 3}
+
+		var seen []Sale
+		for {
+			result, err := page.apply(append([]Sale{}, sales...))
+			require.NoError(t, err)
+			assert.Equal(t, 10, result.Total)
+			seen = append(seen, result.Sales...)
+			if result.NextCursor == "" {
+				break
+			}
+			page.Cursor = result.NextCursor
+		}
+
+		require.Len(t, seen, 10)
+		for i := 1; i < len(seen); i++ {
+			assert.Negative(t, page.compare(seen[i-1], seen[i]), "orden estable sin repetidos")
+		}
+	}
+}
+
+func TestPage_Offset(t *testing.T) {
+	result, err := Page{Limit: 4, Offset: 8}.apply(testSales(10))
+	require.NoError(t, err)
+	require.Len(t, result.Sales, 2)
+	assert.Equal(t, "s08", result.Sales[0].ID)
+	assert.Empty(t, result.NextCursor)
+
+	result, err = Page{Offset: 20}.apply(testSales(10))
+	require.NoError(t, err)
+	assert.Empty(t, result.Sales)
+}
+
+func TestPage_Invalid(t *testing.T) {
+	cases := map[string]Page{
+		"sort desconocido":     {Sort: "user_id"},
+		"limit excesivo":       {Limit: MaxPageLimit + 1},
+		"offset negativo":      {Offset: -1},
+		"cursor y offset":      {Offset: 1, Cursor: "x"},
+		"cursor no decodifica": {Cursor: "%%%"},
+	}
+	for name, page := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := page.apply(testSales(3))
+			assert.ErrorIs(t, err, ErrInvalidPage)
+		})
+	}
+
+	// un cursor emitido para otro orden no se acepta
+	result, err := Page{Limit: 1}.apply(testSales(3))
+	require.NoError(t, err)
+	_, err = Page{Limit: 1, Desc: true, Cursor: result.NextCursor}.apply(testSales(3))
+	assert.ErrorIs(t, err, ErrInvalidPage)
+}
@@ -0,0 +1,143 @@
+package sale
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// RateProvider supplies the exchange rates used to convert amounts between
+// currencies.
+type RateProvider interface {
+	// Rate returns how many units of to are worth one unit of from.
+	// Returns an error wrapping ErrRateNotFound if the pair is unknown.
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// StaticRates is a RateProvider backed by a fixed table of rates against a
+// base currency, typically loaded from a file with LoadStaticRates. Rates
+// between two non-base currencies are derived through the base.
+// It is read-only after construction and safe for concurrent use.
+type StaticRates struct {
+	base  string
+	rates map[string]*big.Rat // unidades de la moneda por unidad de base
+}
+
+// ratesFile is the on-disk format read by LoadStaticRates:
+//
+//	{"base": "USD", "rates": {"ARS": "1050.25", "EUR": 0.92}}
+//
+// Rates may be JSON numbers or strings; both are parsed exactly.
+type ratesFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// NewStaticRates returns a provider where one unit of base is worth
+// rates[c] units of currency c.
+// Returns an error wrapping ErrInvalidCurrency for unknown codes, or
+// ErrRateNotFound for rates that are not positive.
+func NewStaticRates(base string, rates map[string]*big.Rat) (*StaticRates, error) {
+	if !IsCurrency(base) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, base)
+	}
+
+	p := &StaticRates{base: base, rates: map[string]*big.Rat{base: big.NewRat(1, 1)}}
+	for code, rate := range rates {
+		if !IsCurrency(code) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+		}
+		if rate == nil || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("%w: la cotización de %s debe ser positiva", ErrRateNotFound, code)
+		}
+		p.rates[code] = new(big.Rat).Set(rate)
+	}
+	return p, nil
+}
+
+// LoadStaticRates reads a rates table from a JSON file (see ratesFile).
+func LoadStaticRates(path string) (*StaticRates, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f ratesFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("cotizaciones %s: %w", path, err)
+	}
+
+	rates := make(map[string]*big.Rat, len(f.Rates))
+	for code, n := range f.Rates {
+		r, ok := new(big.Rat).SetString(n.String())
+		if !ok {
+			return nil, fmt.Errorf("cotizaciones %s: %s no es un número: %q", path, code, n)
+		}
+		rates[code] = r
+	}
+
+	p, err := NewStaticRates(f.Base, rates)
+	if err != nil {
+		return nil, fmt.Errorf("cotizaciones %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Base returns the currency the rates are expressed against.
+func (p *StaticRates) Base() string {
+	return p.base
+}
+
+// Rate implements RateProvider.
+func (p *StaticRates) Rate(from, to string) (*big.Rat, error) {
+	f, ok := p.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s a %s", ErrRateNotFound, from, to)
+	}
+	t, ok := p.rates[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s a %s", ErrRateNotFound, from, to)
+	}
+	return new(big.Rat).Quo(t, f), nil
+}
+
+// Convert returns m expressed in currency to, using the rate from p and
+// rounding half away from zero to the minor unit of to.
+// Amounts already in to are returned unchanged.
+func Convert(m Money, to string, p RateProvider) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+
+	rate, err := p.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// minor_to = minor_from / 10^dfrom * rate * 10^dto
+	v := new(big.Rat).SetInt64(m.Minor)
+	v.Mul(v, rate)
+	v.Mul(v, new(big.Rat).SetFrac(pow10(minorDigits(to)), pow10(minorDigits(m.Currency))))
+
+	minor := roundHalfAway(v)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s en %s", ErrAmountOverflow, m, m.Currency, to)
+	}
+	return NewMoney(minor.Int64(), to), nil
+}
+
+// roundHalfAway rounds v to the nearest integer, halves away from zero.
+func roundHalfAway(v *big.Rat) *big.Int {
+	num := new(big.Int).Abs(v.Num())
+	den := v.Denom()
+
+	// (2|num| + den) / 2den, truncado
+	q := new(big.Int).Mul(num, big.NewInt(2))
+	q.Add(q, den)
+	q.Quo(q, new(big.Int).Mul(den, big.NewInt(2)))
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
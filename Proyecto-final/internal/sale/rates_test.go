@@ -0,0 +1,79 @@
+package sale
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestRates(t *testing.T) *StaticRates {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base":"USD","rates":{"ARS":"1000","EUR":0.8,"JPY":"150"}}`), 0o600))
+	rates, err := LoadStaticRates(path)
+	require.NoError(t, err)
+	return rates
+}
+
+func TestConvert(t *testing.T) {
+	rates := loadTestRates(t)
+
+	cases := []struct {
+		in   Money
+		to   string
+		want Money
+	}{
+		{NewMoney(1250, "USD"), "ARS", NewMoney(1250000, "ARS")},
+		{NewMoney(100000, "ARS"), "USD", NewMoney(100, "USD")},
+		{NewMoney(100, "EUR"), "USD", NewMoney(125, "USD")},
+		{NewMoney(100, "USD"), "JPY", NewMoney(150, "JPY")},
+		{NewMoney(1, "ARS"), "USD", NewMoney(0, "USD")},   // 0.00001 USD redondea a 0
+		{NewMoney(500, "ARS"), "USD", NewMoney(1, "USD")}, // 0.005 redondea hacia arriba
+		{NewMoney(300, "ARS"), "ARS", NewMoney(300, "ARS")},
+	}
+	for _, c := range cases {
+		got, err := Convert(c.in, c.to, rates)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, got, "%s %s -> %s", c.in, c.in.Currency, c.to)
+	}
+
+	_, err := Convert(NewMoney(100, "BRL"), "USD", rates)
+	assert.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestLoadStaticRates_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"base.json":     `{"base":"XXX","rates":{}}`,
+		"code.json":     `{"base":"USD","rates":{"ARZ":"1"}}`,
+		"negative.json": `{"base":"USD","rates":{"ARS":"-1"}}`,
+		"text.json":     `{"base":"USD","rates":{"ARS":"mucho"}}`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+		_, err := LoadStaticRates(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestService_ConvertTotals(t *testing.T) {
+	totals := map[string]Money{"ARS": NewMoney(200000, "ARS"), "USD": NewMoney(550, "USD")}
+
+	_, err := NewService(NewLocalStorage()).ConvertTotals(totals, "USD")
+	assert.ErrorIs(t, err, ErrNoRateProvider)
+
+	svc := NewService(NewLocalStorage(), WithRateProvider(loadTestRates(t)))
+	sum, err := svc.ConvertTotals(totals, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(750, "USD"), sum)
+
+	_, err = svc.ConvertTotals(totals, "ZZZ")
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+
+	totals["USD"] = NewMoney(math.MaxInt64, "USD")
+	_, err = svc.ConvertTotals(totals, "USD")
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
@@ -1,93 +1,464 @@
 package sale
 
 import (
-	"math/rand"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Service provides high-level sale management operations on a LocalStorage backend.
+// Service provides high-level sale management operations on a Storage backend.
 type Service struct {
 	// storage is the underlying persistence for Sale entities.
-	storage *LocalStorage
+	storage Storage
+	// machine decides which states exist and which changes are allowed.
+	machine *StateMachine
+	// importable are the extra states imported sales may start in.
+	importable map[string]bool
+	// rates converts totals between currencies; nil disables conversion.
+	rates RateProvider
+	// dispatcher is notified after writes that emit events; may be nil.
+	dispatcher *Dispatcher
+	// metrics records created sales and state changes.
+	metrics Metrics
+}
+
+// Option configures optional Service behaviour.
+type Option func(*Service)
+
+// WithStateMachine replaces the default sale lifecycle.
+func WithStateMachine(m *StateMachine) Option {
+	return func(s *Service) {
+		s.machine = m
+	}
+}
+
+// DefaultImportStates are the states, besides those the state machine
+// allows, that imported sales may start in: sales imported from another
+// system were often settled there already.
+var DefaultImportStates = []string{StateApproved, StateRejected}
+
+// WithImportStates replaces DefaultImportStates. It only affects Import,
+// ImportAll and ValidateImport; Create keeps to the state machine.
+func WithImportStates(states ...string) Option {
+	return func(s *Service) {
+		s.importable = map[string]bool{}
+		for _, st := range states {
+			s.importable[st] = true
+		}
+	}
+}
+
+// WithRateProvider enables converting totals between currencies.
+func WithRateProvider(p RateProvider) Option {
+	return func(s *Service) {
+		s.rates = p
+	}
+}
+
+// WithDispatcher makes the service wake d up whenever it writes domain
+// events, so they are delivered right away instead of on the next poll.
+func WithDispatcher(d *Dispatcher) Option {
+	return func(s *Service) {
+		s.dispatcher = d
+	}
+}
+
+// WithMetrics makes the service report created sales and state changes
+// to m.
+func WithMetrics(m Metrics) Option {
+	return func(s *Service) {
+		s.metrics = m
+	}
 }
 
 // NewService creates a new Service.
-func NewService(storage *LocalStorage) *Service {
-	return &Service{
+// Without options it uses DefaultStateMachine and DefaultImportStates.
+func NewService(storage Storage, opts ...Option) *Service {
+	s := &Service{
 		storage: storage,
+		machine: DefaultStateMachine(),
+		metrics: noMetrics{},
+	}
+	WithImportStates(DefaultImportStates...)(s)
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+// StateMachine returns the state machine that drives this service.
+func (s *Service) StateMachine() *StateMachine {
+	return s.machine
 }
 
 // Create adds a brand-new sale to the system.
 // It sets CreatedAt and UpdatedAt to the current time and initializes Version to 1.
-// Returns ErrEmptyID if sale.ID is empty.
+// A sale without Estado starts in the state machine's initial state, and
+// one without currency in DefaultCurrency.
+// A SaleCreated event is written to the outbox along with the sale.
+// Returns the errors described in Validate if the sale is invalid.
 func (s *Service) Create(sale *Sale) error {
+	return s.create(sale, s.machine.CanStart)
+}
+
+// Import is like Create for a sale brought from another system, which may
+// also start in one of the import states (see WithImportStates).
+func (s *Service) Import(sale *Sale) error {
+	return s.create(sale, s.canImport)
+}
+
+func (s *Service) create(sale *Sale, canStart func(string) bool) error {
+	if err := s.validate(*sale, canStart); err != nil {
+		return err
+	}
+	now := time.Now()
+	s.prepare(sale, now)
+
+	if err := s.storage.Set(sale, creationEvents(*sale, now)...); err != nil {
+		return err
+	}
+	s.notify()
+	s.metrics.SaleCreated(*sale)
+	return nil
+}
+
+// CreateAll adds several brand-new sales in a single atomic write: either
+// all of them are stored or none is. Each sale is initialized as in Create.
+// Returns an error wrapping the Validate error of the first invalid sale,
+// storing nothing.
+func (s *Service) CreateAll(sales []*Sale) error {
+	return s.createAll(sales, s.machine.CanStart)
+}
+
+// ImportAll is like CreateAll for sales brought from another system, which
+// may also start in one of the import states (see WithImportStates).
+func (s *Service) ImportAll(sales []*Sale) error {
+	return s.createAll(sales, s.canImport)
+}
+
+func (s *Service) createAll(sales []*Sale, canStart func(string) bool) error {
+	for i, sale := range sales {
+		if err := s.validate(*sale, canStart); err != nil {
+			return fmt.Errorf("venta %d: %w", i, err)
+		}
+	}
+
+	now := time.Now()
+	events := make([]Event, 0, len(sales))
+	for _, sale := range sales {
+		s.prepare(sale, now)
+		events = append(events, creationEvents(*sale, now)...)
+	}
+
+	if err := s.storage.SetAll(sales, events...); err != nil {
+		return err
+	}
+	s.notify()
+	for _, sale := range sales {
+		s.metrics.SaleCreated(*sale)
+	}
+	return nil
+}
+
+// notify wakes the dispatcher up, if any.
+func (s *Service) notify() {
+	if s.dispatcher != nil {
+		s.dispatcher.Notify()
+	}
+}
+
+// Validate reports whether sale could be created, without storing it.
+// Returns ErrInvalidAmount if the amount is not positive,
+// ErrInvalidCurrency if the currency is not an ISO 4217 code,
+// ErrInvalidNewState if sale.Estado is not a declared state, or
+// ErrInvalidStartState if sales may not be created in that state.
+func (s *Service) Validate(sale Sale) error {
+	return s.validate(sale, s.machine.CanStart)
+}
+
+// ValidateImport is like Validate for a sale to be imported, which may also
+// start in one of the import states.
+func (s *Service) ValidateImport(sale Sale) error {
+	return s.validate(sale, s.canImport)
+}
+
+// canImport reports whether an imported sale may start in state.
+func (s *Service) canImport(state string) bool {
+	return s.machine.CanStart(state) || s.importable[state]
+}
+
+func (s *Service) validate(sale Sale, canStart func(string) bool) error {
+	if !sale.Amount.IsPositive() {
+		return ErrInvalidAmount
+	}
+	if sale.Amount.Currency != "" && !IsCurrency(sale.Amount.Currency) {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, sale.Amount.Currency)
+	}
+	if sale.Estado != "" {
+		if !s.machine.IsValid(sale.Estado) {
+			return ErrInvalidNewState
+		}
+		if !canStart(sale.Estado) {
+			return fmt.Errorf("%w: %s", ErrInvalidStartState, sale.Estado)
+		}
+	}
+	return nil
+}
+
+// prepare fills in the fields of a validated sale that are assigned on
+// creation.
+func (s *Service) prepare(sale *Sale, now time.Time) {
 	sale.ID = uuid.NewString()
-	// esto es asi porque sino los test de update fallan al crear aleatorio
+	if sale.Amount.Currency == "" {
+		sale.Amount.Currency = DefaultCurrency
+	}
 	if sale.Estado == "" {
-		estado := []string{"pending", "approved", "rejected"}
-		sale.Estado = estado[rand.Intn(3)]
+		sale.Estado = s.machine.Initial()
 	}
-	now := time.Now()
 	sale.CreatedAt = now
 	sale.UpdatedAt = now
 	sale.Version = 1
-
-	return s.storage.Set(sale)
 }
 
 // Get retrieves a sale by its ID.
 // Returns ErrNotFound if no sale exists with the given ID.
+// Soft-deleted sales are reported as not found; see GetWithDeleted.
 func (s *Service) Get(id string) (*Sale, error) {
+	sale, err := s.storage.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	if sale.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return sale, nil
+}
+
+// GetWithDeleted retrieves a sale by its ID even if it was soft-deleted.
+// Returns ErrNotFound if no sale exists with the given ID.
+func (s *Service) GetWithDeleted(id string) (*Sale, error) {
 	return s.storage.Read(id)
 }
 
 // Update modifies an existing sale's data.
-// It updates Name, Address, NickName, sets UpdatedAt to now and increments Version.
-// Returns ErrNotFound if the sale does not exist, or ErrEmptyID if sale.ID is empty.
+// It updates Estado, sets UpdatedAt to now, increments Version and records
+// the change, made by sale.Actor, in the sale history.
+// The change must be allowed by the state machine (see StateMachine.Transition).
+// Moving to StateApproved or StateRejected writes a SaleApproved or
+// SaleRejected event to the outbox in the same atomic write.
+// Returns ErrSaleNotFound if the sale does not exist, or ErrVersionConflict
+// if sale.Version is set and stale or a concurrent update won the race.
+// Other storage errors are returned as they are.
 func (s *Service) Update(id string, sale *UpdateFields) (*Sale, error) {
 	existing, err := s.storage.Read(id)
-	// controlo existencia
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrSaleNotFound
+	}
 	if err != nil {
+		return nil, err
+	}
+	// las ventas borradas no se pueden modificar
+	if existing.DeletedAt != nil {
 		return nil, ErrSaleNotFound
 	}
-	// reviso que el estado anterior es valido
-	if existing.Estado != "pending" {
-		return nil, ErrInvalidStateChange //Solo permite cambiar si el estado anterior es == pending
+	// control optimista: el cliente pudo haber leído una versión vieja
+	if sale.Version != nil && *sale.Version != existing.Version {
+		return nil, ErrVersionConflict
 	}
-	// me fijo que el estado nuevo es de los dos validos
-	if !(sale.Estado == "approved" || sale.Estado == "rejected") {
-		return nil, ErrInvalidNewState
+	// la máquina de estados valida el destino, la transición y sus guards
+	if err := s.machine.Transition(*existing, sale.Estado); err != nil {
+		return nil, err
 	}
 
+	version := existing.Version
+	change := &StateChange{
+		SaleID:  existing.ID,
+		From:    existing.Estado,
+		To:      sale.Estado,
+		At:      time.Now(),
+		Actor:   sale.Actor,
+		Version: version + 1,
+	}
 	existing.Estado = sale.Estado
-	existing.UpdatedAt = time.Now()
+	existing.UpdatedAt = change.At
 	existing.Version++
 
-	if err := s.storage.Set(existing); err != nil {
+	// si otra request actualizó la venta entre el Read y este punto, falla
+	events := transitionEvents(*existing, sale.Estado, sale.Actor, change.At)
+	if err := s.storage.CompareAndSet(existing, version, change, events...); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrSaleNotFound
+		}
 		return nil, err
 	}
+	if len(events) > 0 {
+		s.notify()
+	}
+	if change.From != change.To {
+		s.metrics.SaleStateChanged(*existing, change.From)
+	}
 
 	return existing, nil
 }
 
-// Delete removes a sale from the system by its ID.
+// History returns every state change recorded for a sale, oldest first.
+// Returns ErrNotFound if no sale exists with the given ID.
+func (s *Service) History(id string) ([]StateChange, error) {
+	return s.storage.History(id)
+}
+
+// Delete soft-deletes a sale: it sets DeletedAt and UpdatedAt to now,
+// increments Version and records a change to HistoryDeleted, made by
+// actor, in the sale history. The sale is hidden from Get, Update and
+// listings but kept, with its history, for audit. When version is non-nil
+// the sale is deleted only if it still has that version.
+// Returns ErrNotFound if the sale does not exist or was already deleted,
+// or ErrVersionConflict if version is stale or a concurrent update won
+// the race.
+func (s *Service) Delete(id string, version *int, actor string) error {
+	existing, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if version != nil && *version != existing.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now()
+	current := existing.Version
+	change := &StateChange{
+		SaleID:  existing.ID,
+		From:    existing.Estado,
+		To:      HistoryDeleted,
+		At:      now,
+		Actor:   actor,
+		Version: current + 1,
+	}
+	existing.DeletedAt = &now
+	existing.UpdatedAt = now
+	existing.Version++
+
+	return s.storage.CompareAndSet(existing, current, change)
+}
+
+// Purge removes a sale and its history for good, whether or not it was
+// soft-deleted. It is meant for administrative clean-ups only.
 // Returns ErrNotFound if the sale does not exist.
-func (s *Service) Delete(id string) error {
+func (s *Service) Purge(id string) error {
 	return s.storage.Delete(id)
 }
 
-// Crear endpoint GET /sales con filtros por user_id y status.
-func (s *Service) ListByUserAndStatus(userID, status string) ([]Sale, error) {
-	var filtered []Sale
+// Find returns every sale that matches filter, in no particular order.
+// Returns an error wrapping ErrInvalidFilter if the filter is invalid.
+func (s *Service) Find(filter Filter) ([]Sale, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	all, err := s.storage.GetAll()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sale := range s.storage.GetAll() {
-		print("usuario", sale.UserID)
-		if sale.UserID == userID && (status == "" || sale.Estado == status) {
+	filtered := []Sale{}
+	for _, sale := range all {
+		if filter.Match(sale) {
 			filtered = append(filtered, sale)
 		}
 	}
 	return filtered, nil
 }
+
+// Export calls fn for every sale that matches filter, in no particular
+// order, streaming them from storage instead of loading them all at once.
+// It stops at the first error returned by fn and returns it.
+// Returns an error wrapping ErrInvalidFilter, before calling fn, if the
+// filter is invalid.
+func (s *Service) Export(filter Filter, fn func(Sale) error) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	return s.storage.Scan(func(sale Sale) error {
+		if !filter.Match(sale) {
+			return nil
+		}
+		return fn(sale)
+	})
+}
+
+// List returns one page of the sales that match filter, sorted and cut
+// according to page, with the stats of every matching sale.
+// Returns an error wrapping ErrInvalidFilter or ErrInvalidPage if the
+// parameters are invalid, or ErrAmountOverflow if a total does not fit in
+// Money.Minor.
+func (s *Service) List(filter Filter, page Page) (*PageResult, error) {
+	sales, err := s.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := page.apply(sales)
+	if err != nil {
+		return nil, err
+	}
+	agg := MustNewAggregator(GroupByStatus)
+	for _, sale := range sales {
+		if err := agg.Add(sale); err != nil {
+			return nil, err
+		}
+	}
+	result.Stats = agg.Stats()
+	return result, nil
+}
+
+// Stats aggregates the sales that match filter, grouped by groupBy (one of
+// the GroupBy constants).
+// Returns an error wrapping ErrInvalidFilter or ErrInvalidGroupBy if the
+// parameters are invalid, or ErrAmountOverflow if a sum does not fit in
+// Money.Minor.
+func (s *Service) Stats(filter Filter, groupBy string) (*Stats, error) {
+	agg, err := NewAggregator(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := s.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if err := agg.Add(sale); err != nil {
+			return nil, err
+		}
+	}
+	return agg.Stats(), nil
+}
+
+// ConvertTotals converts every amount in totals to currency and adds them up.
+// Returns ErrNoRateProvider if the service was built without
+// WithRateProvider, an error wrapping ErrRateNotFound if some currency
+// has no known rate, or one wrapping ErrAmountOverflow if the sum does not
+// fit in Money.Minor.
+func (s *Service) ConvertTotals(totals map[string]Money, currency string) (Money, error) {
+	if s.rates == nil {
+		return Money{}, ErrNoRateProvider
+	}
+	if !IsCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+
+	sum := NewMoney(0, currency)
+	for _, total := range totals {
+		converted, err := Convert(total, currency, s.rates)
+		if err != nil {
+			return Money{}, err
+		}
+		if sum, err = sum.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum, nil
+}
@@ -0,0 +1,249 @@
+package sale
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteStorage(t *testing.T) (*SQLiteStorage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sales.db")
+	s, err := NewSQLiteStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func TestSQLiteStorage_CRUD(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+
+	now := time.Now()
+	in := &Sale{ID: "s1", UserID: "u1", Estado: "pending", Amount: NewMoney(15050, "USD"), CreatedAt: now, UpdatedAt: now, Version: 1}
+	require.NoError(t, s.Set(in))
+
+	got, err := s.Read("s1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", got.UserID)
+	assert.Equal(t, NewMoney(15050, "USD"), got.Amount)
+	assert.True(t, now.Equal(got.CreatedAt))
+
+	in.Estado = "approved"
+	in.Version = 2
+	require.NoError(t, s.Set(in))
+
+	all, err := s.GetAll()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "approved", all[0].Estado)
+	assert.Equal(t, 2, all[0].Version)
+
+	require.NoError(t, s.Delete("s1"))
+	_, err = s.Read("s1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, s.Delete("s1"), ErrNotFound)
+	assert.ErrorIs(t, s.Set(&Sale{}), ErrEmptyID)
+}
+
+func TestSQLiteStorage_PersistsAcrossReopen(t *testing.T) {
+	s, path := newTestSQLiteStorage(t)
+	require.NoError(t, s.Set(&Sale{ID: "s1", UserID: "u1", Estado: "pending", Amount: NewMoney(1000, DefaultCurrency), Version: 1}))
+	require.NoError(t, s.Close())
+
+	// reabrir no debe volver a aplicar migraciones ni perder datos
+	reopened, err := NewSQLiteStorage(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	got, err := reopened.Read("s1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", got.UserID)
+}
+
+func TestSQLiteStorage_CompareAndSetRecordsHistory(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+	require.NoError(t, s.Set(&Sale{ID: "s1", UserID: "u1", Estado: "pending", Amount: NewMoney(1000, DefaultCurrency), Version: 1}))
+
+	change := &StateChange{SaleID: "s1", From: "pending", To: "approved", At: time.Now(), Actor: "ana", Version: 2}
+	require.NoError(t, s.CompareAndSet(&Sale{ID: "s1", UserID: "u1", Estado: "approved", Amount: NewMoney(1000, DefaultCurrency), Version: 2}, 1, change))
+
+	// una segunda escritura con la versión vieja no debe dejar rastro
+	err := s.CompareAndSet(&Sale{ID: "s1", UserID: "u1", Estado: "rejected", Amount: NewMoney(1000, DefaultCurrency), Version: 2}, 1, change)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.ErrorIs(t, s.CompareAndSet(&Sale{ID: "nope", Version: 2}, 1, nil), ErrNotFound)
+
+	history, err := s.History("s1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "ana", history[0].Actor)
+	assert.Equal(t, "approved", history[0].To)
+	assert.Equal(t, 2, history[0].Version)
+
+	_, err = s.History("nope")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSQLiteStorage_MigratesFloatAmounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.db")
+
+	// base con el esquema anterior a los montos en unidades menores
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE schema_migrations (version INTEGER NOT NULL)`)
+	require.NoError(t, err)
+	for i, m := range migrations[:4] {
+		_, err = db.Exec(m)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1)
+		require.NoError(t, err)
+	}
+	now := formatTime(time.Now())
+	_, err = db.Exec(`INSERT INTO sales VALUES ('s1', 'u1', 'pending', 150.55, ?, ?, 1)`, now, now)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := NewSQLiteStorage(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	got, err := s.Read("s1")
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(15055, DefaultCurrency), got.Amount)
+}
+
+func TestSQLiteStorage_ScanBatches(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+
+	// más de dos lotes completos para recorrer el paginado por ID
+	n := 2*scanBatchSize + 1
+	for i := range n {
+		require.NoError(t, s.Set(&Sale{ID: fmt.Sprintf("s%04d", i), UserID: "u1", Estado: "pending", Amount: NewMoney(100, DefaultCurrency), Version: 1}))
+	}
+
+	seen := map[string]bool{}
+	require.NoError(t, s.Scan(func(sale Sale) error {
+		seen[sale.ID] = true
+		return nil
+	}))
+	assert.Len(t, seen, n)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.Scan(func(Sale) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestService_CreateAllIsAtomic(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+	svc := NewService(s)
+
+	err := svc.CreateAll([]*Sale{
+		{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)},
+		{UserID: "u2", Amount: NewMoney(0, DefaultCurrency)},
+	})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	all, err := s.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	batch := []*Sale{
+		{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)},
+		{UserID: "u2", Amount: NewMoney(200, "USD"), Estado: StateApproved},
+	}
+	require.NoError(t, svc.ImportAll(batch))
+	all, err = s.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, StatePending, batch[0].Estado)
+	assert.NotEqual(t, batch[0].ID, batch[1].ID)
+
+	assert.ErrorIs(t, s.SetAll([]*Sale{{ID: "x"}, {}}), ErrEmptyID)
+	_, err = s.Read("x")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_SoftDelete(t *testing.T) {
+	s, path := newTestSQLiteStorage(t)
+	svc := NewService(s)
+
+	sale := &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(sale))
+	_, err := svc.Update(sale.ID, &UpdateFields{Estado: StateApproved, Actor: "backoffice"})
+	require.NoError(t, err)
+
+	stale := 1
+	assert.ErrorIs(t, svc.Delete(sale.ID, &stale, "backoffice"), ErrVersionConflict)
+	require.NoError(t, svc.Delete(sale.ID, nil, "backoffice"))
+	assert.ErrorIs(t, svc.Delete(sale.ID, nil, "backoffice"), ErrNotFound)
+
+	// oculta para lecturas, cambios y listados...
+	_, err = svc.Get(sale.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = svc.Update(sale.ID, &UpdateFields{Estado: StateRejected})
+	assert.ErrorIs(t, err, ErrSaleNotFound)
+	found, err := svc.Find(Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// ...pero se conserva para auditoría, también tras reabrir la base
+	require.NoError(t, s.Close())
+	reopened, err := NewSQLiteStorage(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	svc = NewService(reopened)
+
+	deleted, err := svc.GetWithDeleted(sale.ID)
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+	assert.Equal(t, 3, deleted.Version)
+	found, err = svc.Find(Filter{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	history, err := svc.History(sale.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	// el borrado queda en el historial con quién y cuándo
+	assert.Equal(t, StateApproved, history[1].From)
+	assert.Equal(t, HistoryDeleted, history[1].To)
+	assert.Equal(t, "backoffice", history[1].Actor)
+	assert.Equal(t, 3, history[1].Version)
+	assert.Equal(t, *deleted.DeletedAt, history[1].At)
+
+	require.NoError(t, svc.Purge(sale.ID))
+	_, err = svc.GetWithDeleted(sale.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, svc.Purge(sale.ID), ErrNotFound)
+}
+
+func TestService_UpdateStorageError(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+	svc := NewService(s)
+
+	sale := &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency)}
+	require.NoError(t, svc.Create(sale))
+	require.NoError(t, s.Close())
+
+	// un error de la base no se disfraza de venta inexistente
+	_, err := svc.Update(sale.ID, &UpdateFields{Estado: StateApproved})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrSaleNotFound)
+}
+
+func TestSQLiteStorage_Ping(t *testing.T) {
+	s, _ := newTestSQLiteStorage(t)
+	require.NoError(t, s.Ping(context.Background()))
+
+	require.NoError(t, s.Close())
+	assert.Error(t, s.Ping(context.Background()))
+}
@@ -0,0 +1,137 @@
+package sale
+
+import "fmt"
+
+// Sale states declared by DefaultStateMachine.
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateRejected = "rejected"
+)
+
+// Guard is evaluated before a transition is applied. Returning a non-nil
+// error vetoes the transition; the error is wrapped in ErrTransitionRejected.
+type Guard func(sale Sale, from, to string) error
+
+// StateMachine declares the states a sale can be in, the states new sales
+// may start in, and which transitions between states are allowed.
+//
+// A StateMachine must be fully configured before it is handed to
+// NewService; it is read-only afterwards and therefore safe for concurrent use.
+type StateMachine struct {
+	initial     string
+	states      []string
+	startable   map[string]bool
+	transitions map[string]map[string][]Guard
+}
+
+// NewStateMachine creates a state machine whose only state is initial.
+func NewStateMachine(initial string) *StateMachine {
+	m := &StateMachine{
+		initial:     initial,
+		startable:   map[string]bool{initial: true},
+		transitions: map[string]map[string][]Guard{},
+	}
+	return m.AddState(initial)
+}
+
+// DefaultStateMachine returns the standard sale lifecycle:
+// pending -> approved and pending -> rejected. New sales start pending;
+// Service.Import may also start them in DefaultImportStates.
+func DefaultStateMachine() *StateMachine {
+	return NewStateMachine(StatePending).
+		AddTransition(StatePending, StateApproved).
+		AddTransition(StatePending, StateRejected)
+}
+
+// AddState declares states that have no transitions yet.
+// Declaring an existing state is a no-op.
+func (m *StateMachine) AddState(states ...string) *StateMachine {
+	for _, st := range states {
+		if !m.IsValid(st) {
+			m.states = append(m.states, st)
+		}
+	}
+	return m
+}
+
+// AddTransition allows moving a sale from one state to another, subject to
+// the given guards. Both states are declared if they were not already.
+func (m *StateMachine) AddTransition(from, to string, guards ...Guard) *StateMachine {
+	m.AddState(from, to)
+	if m.transitions[from] == nil {
+		m.transitions[from] = map[string][]Guard{}
+	}
+	m.transitions[from][to] = append(m.transitions[from][to], guards...)
+	return m
+}
+
+// AllowStart lets new sales be created in states other than the initial
+// one. The states are declared if they were not already.
+func (m *StateMachine) AllowStart(states ...string) *StateMachine {
+	m.AddState(states...)
+	for _, st := range states {
+		m.startable[st] = true
+	}
+	return m
+}
+
+// CanStart reports whether a new sale may be created in state.
+func (m *StateMachine) CanStart(state string) bool {
+	return m.startable[state]
+}
+
+// Initial returns the state assigned to sales created without one.
+func (m *StateMachine) Initial() string {
+	return m.initial
+}
+
+// States returns every declared state, in declaration order.
+func (m *StateMachine) States() []string {
+	return append([]string(nil), m.states...)
+}
+
+// IsValid reports whether state has been declared.
+func (m *StateMachine) IsValid(state string) bool {
+	for _, st := range m.states {
+		if st == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition checks whether sale may move to state to.
+// Returns ErrInvalidNewState if to is not a declared state,
+// ErrInvalidStateChange if there is no transition from the sale's current
+// state, or ErrTransitionRejected if a guard vetoes it.
+func (m *StateMachine) Transition(sale Sale, to string) error {
+	if !m.IsValid(to) {
+		return ErrInvalidNewState
+	}
+
+	guards, ok := m.transitions[sale.Estado][to]
+	if !ok {
+		return ErrInvalidStateChange
+	}
+
+	for _, guard := range guards {
+		if err := guard(sale, sale.Estado, to); err != nil {
+			return fmt.Errorf("%w: %v", ErrTransitionRejected, err)
+		}
+	}
+
+	return nil
+}
+
+// MaxAmount returns a guard that vetoes the transition for sales in the
+// currency of limit whose amount is greater than limit. Sales in other
+// currencies are not affected.
+func MaxAmount(limit Money) Guard {
+	return func(sale Sale, from, to string) error {
+		if sale.Amount.Currency == limit.Currency && sale.Amount.Cmp(limit) > 0 {
+			return fmt.Errorf("el monto %s supera el máximo de %s %s para pasar a %s", sale.Amount, limit, limit.Currency, to)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package sale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultStateMachine(t *testing.T) {
+	m := DefaultStateMachine()
+
+	assert.Equal(t, StatePending, m.Initial())
+	assert.Equal(t, []string{StatePending, StateApproved, StateRejected}, m.States())
+
+	assert.NoError(t, m.Transition(Sale{Estado: StatePending}, StateApproved))
+	assert.NoError(t, m.Transition(Sale{Estado: StatePending}, StateRejected))
+	assert.ErrorIs(t, m.Transition(Sale{Estado: StateApproved}, StateRejected), ErrInvalidStateChange)
+	assert.ErrorIs(t, m.Transition(Sale{Estado: StatePending}, "cancelled"), ErrInvalidNewState)
+}
+
+func TestStateMachine_ExtendedWithGuards(t *testing.T) {
+	m := DefaultStateMachine().
+		AddTransition(StatePending, "cancelled").
+		AddTransition(StateApproved, "refunded", MaxAmount(NewMoney(50000, DefaultCurrency)))
+
+	svc := NewService(NewLocalStorage(), WithStateMachine(m))
+
+	s := &Sale{UserID: "u1", Amount: NewMoney(100000, DefaultCurrency)}
+	require.NoError(t, svc.Create(s))
+	require.Equal(t, StatePending, s.Estado)
+
+	_, err := svc.Update(s.ID, &UpdateFields{Estado: StateApproved})
+	require.NoError(t, err)
+
+	// el guard impide reembolsar montos mayores a 500
+	_, err = svc.Update(s.ID, &UpdateFields{Estado: "refunded"})
+	assert.ErrorIs(t, err, ErrTransitionRejected)
+
+	small := &Sale{UserID: "u1", Amount: NewMoney(10000, DefaultCurrency)}
+	require.NoError(t, svc.Create(small))
+	_, err = svc.Update(small.ID, &UpdateFields{Estado: "cancelled"})
+	require.NoError(t, err)
+}
+
+func TestService_CreateStartStates(t *testing.T) {
+	m := NewStateMachine(StatePending).AddTransition(StatePending, StateApproved)
+	svc := NewService(NewLocalStorage(), WithStateMachine(m))
+
+	err := svc.Create(&Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency), Estado: StateApproved})
+	assert.ErrorIs(t, err, ErrInvalidStartState)
+
+	m.AllowStart(StateApproved)
+	require.NoError(t, svc.Create(&Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency), Estado: StateApproved}))
+	assert.False(t, DefaultStateMachine().CanStart(StateRejected))
+}
+
+func TestService_ImportStartStates(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+	approved := func() *Sale {
+		return &Sale{UserID: "u1", Amount: NewMoney(100, DefaultCurrency), Estado: StateApproved}
+	}
+
+	// crear salteando pending no está permitido; importar sí
+	assert.ErrorIs(t, svc.Create(approved()), ErrInvalidStartState)
+	assert.ErrorIs(t, svc.Validate(*approved()), ErrInvalidStartState)
+	assert.ErrorIs(t, svc.CreateAll([]*Sale{approved()}), ErrInvalidStartState)
+	require.NoError(t, svc.ValidateImport(*approved()))
+	require.NoError(t, svc.Import(approved()))
+	require.NoError(t, svc.ImportAll([]*Sale{approved(), {UserID: "u2", Amount: NewMoney(100, DefaultCurrency)}}))
+
+	strict := NewService(NewLocalStorage(), WithImportStates(StateRejected))
+	assert.ErrorIs(t, strict.Import(approved()), ErrInvalidStartState)
+}
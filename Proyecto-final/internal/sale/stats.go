@@ -0,0 +1,195 @@
+package sale
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Groupings supported by Aggregator. Time groupings use the sale's
+// CreatedAt in UTC.
+const (
+	GroupByNone   = ""
+	GroupByUser   = "user"
+	GroupByStatus = "status"
+	GroupByDay    = "day"   // key 2006-01-02
+	GroupByWeek   = "week"  // ISO 8601 week, key 2006-W01
+	GroupByMonth  = "month" // key 2006-01
+)
+
+// AmountStats summarizes the amounts of a set of sales in one currency.
+type AmountStats struct {
+	Count int   `json:"count"`
+	Sum   Money `json:"sum"`
+	// Avg is Sum / Count rounded half away from zero to the minor unit.
+	Avg Money `json:"avg"`
+	Min Money `json:"min"`
+	Max Money `json:"max"`
+}
+
+// Group summarizes the sales sharing one grouping key.
+// Amounts are kept per currency because they cannot be added together.
+type Group struct {
+	Key     string                  `json:"key"`
+	Count   int                     `json:"count"`
+	Amounts map[string]*AmountStats `json:"amounts"`
+}
+
+// Stats is the result of aggregating sales with an Aggregator.
+type Stats struct {
+	GroupBy string `json:"group_by"`
+	// Total summarizes every sale, regardless of grouping.
+	Total Group `json:"total"`
+	// Groups holds one entry per key, ordered by key. It is empty when
+	// GroupBy is GroupByNone.
+	Groups []Group `json:"groups"`
+}
+
+// Group returns the group with the given key, or nil if no sale had it.
+func (s *Stats) Group(key string) *Group {
+	for i := range s.Groups {
+		if s.Groups[i].Key == key {
+			return &s.Groups[i]
+		}
+	}
+	return nil
+}
+
+// Sums returns the sum of the group's amounts per currency.
+func (g Group) Sums() map[string]Money {
+	sums := make(map[string]Money, len(g.Amounts))
+	for currency, st := range g.Amounts {
+		sums[currency] = st.Sum
+	}
+	return sums
+}
+
+// Aggregator computes Stats incrementally, one sale at a time, so sales
+// never need to be held in memory all at once. It is not safe for
+// concurrent use.
+type Aggregator struct {
+	groupBy string
+	total   Group
+	groups  map[string]*Group
+}
+
+// NewAggregator returns an empty aggregator grouping by groupBy.
+// Returns an error wrapping ErrInvalidGroupBy for unknown groupings.
+func NewAggregator(groupBy string) (*Aggregator, error) {
+	switch groupBy {
+	case GroupByNone, GroupByUser, GroupByStatus, GroupByDay, GroupByWeek, GroupByMonth:
+	default:
+		return nil, fmt.Errorf("%w: no se puede agrupar por %q", ErrInvalidGroupBy, groupBy)
+	}
+	return &Aggregator{
+		groupBy: groupBy,
+		total:   Group{Amounts: map[string]*AmountStats{}},
+		groups:  map[string]*Group{},
+	}, nil
+}
+
+// MustNewAggregator is like NewAggregator but panics on an unknown
+// grouping. It is meant for groupings fixed in code.
+func MustNewAggregator(groupBy string) *Aggregator {
+	a, err := NewAggregator(groupBy)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Add accumulates sale into the totals and into its group.
+// Returns an error wrapping ErrAmountOverflow if a sum no longer fits in
+// Money.Minor; the stats are then incomplete and the aggregator should be
+// discarded.
+func (a *Aggregator) Add(sale Sale) error {
+	if err := a.total.add(sale); err != nil {
+		return err
+	}
+	if a.groupBy == GroupByNone {
+		return nil
+	}
+
+	key := a.key(sale)
+	g, ok := a.groups[key]
+	if !ok {
+		g = &Group{Key: key, Amounts: map[string]*AmountStats{}}
+		a.groups[key] = g
+	}
+	return g.add(sale)
+}
+
+// Stats returns the aggregation of every sale added so far.
+func (a *Aggregator) Stats() *Stats {
+	stats := &Stats{
+		GroupBy: a.groupBy,
+		Total:   a.total.finish(),
+		Groups:  make([]Group, 0, len(a.groups)),
+	}
+	for _, g := range a.groups {
+		stats.Groups = append(stats.Groups, g.finish())
+	}
+	slices.SortFunc(stats.Groups, func(x, y Group) int {
+		return strings.Compare(x.Key, y.Key)
+	})
+	return stats
+}
+
+func (a *Aggregator) key(sale Sale) string {
+	at := sale.CreatedAt.UTC()
+	switch a.groupBy {
+	case GroupByUser:
+		return sale.UserID
+	case GroupByStatus:
+		return sale.Estado
+	case GroupByDay:
+		return at.Format("2006-01-02")
+	case GroupByWeek:
+		year, week := at.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	default: // GroupByMonth
+		return at.Format("2006-01")
+	}
+}
+
+// add accumulates sale into g. It leaves g unchanged if the sum of its
+// currency overflows.
+func (g *Group) add(sale Sale) error {
+	amount := sale.Amount
+	st, ok := g.Amounts[amount.Currency]
+	if !ok {
+		g.Count++
+		g.Amounts[amount.Currency] = &AmountStats{
+			Count: 1, Sum: amount, Min: amount, Max: amount,
+		}
+		return nil
+	}
+	sum, err := st.Sum.Add(amount)
+	if err != nil {
+		return err
+	}
+	g.Count++
+	st.Count++
+	st.Sum = sum
+	if amount.Minor < st.Min.Minor {
+		st.Min = amount
+	}
+	if amount.Minor > st.Max.Minor {
+		st.Max = amount
+	}
+	return nil
+}
+
+// finish returns a copy of g with averages computed, so the aggregator can
+// keep accumulating afterwards.
+func (g *Group) finish() Group {
+	out := Group{Key: g.Key, Count: g.Count, Amounts: make(map[string]*AmountStats, len(g.Amounts))}
+	for currency, st := range g.Amounts {
+		c := *st
+		avg := roundHalfAway(new(big.Rat).SetFrac64(c.Sum.Minor, int64(c.Count)))
+		c.Avg = NewMoney(avg.Int64(), currency)
+		out.Amounts[currency] = &c
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package sale
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsFixture() []Sale {
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC) }
+	return []Sale{
+		{UserID: "u1", Estado: StatePending, Amount: NewMoney(1000, "ARS"), CreatedAt: day(2)},
+		{UserID: "u1", Estado: StateApproved, Amount: NewMoney(2001, "ARS"), CreatedAt: day(2)},
+		{UserID: "u2", Estado: StateApproved, Amount: NewMoney(500, "USD"), CreatedAt: day(9)},
+		{UserID: "u2", Estado: StatePending, Amount: NewMoney(4000, "ARS"), CreatedAt: day(31)},
+	}
+}
+
+func aggregate(t *testing.T, groupBy string) *Stats {
+	agg, err := NewAggregator(groupBy)
+	require.NoError(t, err)
+	for _, s := range statsFixture() {
+		require.NoError(t, agg.Add(s))
+	}
+	return agg.Stats()
+}
+
+func TestAggregator_Totals(t *testing.T) {
+	stats := aggregate(t, GroupByNone)
+
+	assert.Equal(t, 4, stats.Total.Count)
+	assert.Empty(t, stats.Groups)
+	assert.Equal(t, &AmountStats{
+		Count: 3,
+		Sum:   NewMoney(7001, "ARS"),
+		Avg:   NewMoney(2334, "ARS"), // 2333.67 redondeado
+		Min:   NewMoney(1000, "ARS"),
+		Max:   NewMoney(4000, "ARS"),
+	}, stats.Total.Amounts["ARS"])
+	assert.Equal(t, map[string]Money{"ARS": NewMoney(7001, "ARS"), "USD": NewMoney(500, "USD")}, stats.Total.Sums())
+}
+
+func TestAggregator_GroupBy(t *testing.T) {
+	keys := func(s *Stats) []string {
+		var out []string
+		for _, g := range s.Groups {
+			out = append(out, g.Key)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"u1", "u2"}, keys(aggregate(t, GroupByUser)))
+	assert.Equal(t, []string{StateApproved, StatePending}, keys(aggregate(t, GroupByStatus)))
+	assert.Equal(t, []string{"2026-03-02", "2026-03-09", "2026-03-31"}, keys(aggregate(t, GroupByDay)))
+	assert.Equal(t, []string{"2026-W10", "2026-W11", "2026-W14"}, keys(aggregate(t, GroupByWeek)))
+	assert.Equal(t, []string{"2026-03"}, keys(aggregate(t, GroupByMonth)))
+
+	byUser := aggregate(t, GroupByUser)
+	u2 := byUser.Group("u2")
+	require.NotNil(t, u2)
+	assert.Equal(t, 2, u2.Count)
+	assert.Equal(t, NewMoney(500, "USD"), u2.Amounts["USD"].Sum)
+	assert.Nil(t, byUser.Group("u3"))
+
+	assert.Panics(t, func() { MustNewAggregator("year") })
+	_, err := NewAggregator("year")
+	assert.ErrorIs(t, err, ErrInvalidGroupBy)
+}
+
+func TestAggregator_Overflow(t *testing.T) {
+	agg := MustNewAggregator(GroupByStatus)
+	require.NoError(t, agg.Add(Sale{Estado: StatePending, Amount: NewMoney(math.MaxInt64, "ARS")}))
+
+	// la suma no entra en un int64: se informa en lugar de dar la vuelta
+	err := agg.Add(Sale{Estado: StatePending, Amount: NewMoney(1, "ARS")})
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	stats := agg.Stats()
+	assert.Equal(t, 1, stats.Total.Count)
+	assert.Equal(t, NewMoney(math.MaxInt64, "ARS"), stats.Total.Amounts["ARS"].Avg)
+
+	_, err = Totals([]Sale{
+		{Amount: NewMoney(math.MaxInt64, "USD")},
+		{Amount: NewMoney(math.MaxInt64, "USD")},
+	})
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
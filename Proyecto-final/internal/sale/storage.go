@@ -1,6 +1,10 @@
 package sale
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"sync"
+)
 
 // ErrNotFound is returned when a sale with the given ID is not found.
 var ErrNotFound = errors.New("sale not found")
@@ -8,59 +12,260 @@ var ErrNotFound = errors.New("sale not found")
 // ErrEmptyID is returned when trying to store a sale with an empty ID.
 var ErrEmptyID = errors.New("empty sale ID")
 
+// ErrVersionConflict is returned by a conditional write when the stored
+// sale no longer has the expected version.
+var ErrVersionConflict = errors.New("sale version conflict")
+
+// Storage is the persistence contract used by Service.
+// Implementations must return ErrNotFound for unknown IDs and ErrEmptyID
+// when asked to store a sale without an ID.
+type Storage interface {
+	// Set stores or updates a sale. The events are appended to the outbox
+	// in the same atomic write.
+	Set(sale *Sale, events ...Event) error
+	// SetAll stores or updates several sales atomically: if any of them
+	// cannot be stored, none is, and neither are the events.
+	SetAll(sales []*Sale, events ...Event) error
+	// CompareAndSet replaces a stored sale only if its current version
+	// equals version, returning ErrVersionConflict otherwise. A non-nil
+	// change is appended to the sale history, and the events to the
+	// outbox, in the same atomic write.
+	CompareAndSet(sale *Sale, version int, change *StateChange, events ...Event) error
+	// History returns the recorded state changes of a sale, oldest first.
+	History(id string) ([]StateChange, error)
+	// Read retrieves a sale by ID.
+	Read(id string) (*Sale, error)
+	// Delete removes a sale and its history by ID.
+	Delete(id string) error
+	// GetAll returns every stored sale.
+	GetAll() ([]Sale, error)
+	// PendingEvents returns up to limit outbox events that have not been
+	// marked as dispatched, in Seq order.
+	PendingEvents(limit int) ([]Event, error)
+	// MarkDispatched removes events from the pending outbox by Seq.
+	MarkDispatched(seqs ...int64) error
+	// Scan calls fn for every stored sale, in no particular order, without
+	// loading them all at once. It stops at the first error returned by fn
+	// and returns it. Sales written while a scan is running may or may not
+	// be visited.
+	Scan(fn func(Sale) error) error
+	// Ping reports whether the storage can serve requests.
+	Ping(ctx context.Context) error
+}
+
 // LocalStorage provides an in-memory implementation for storing sales.
+// It is safe for concurrent use: every method takes the internal lock and
+// values are copied on the way in and out, so callers never share memory
+// with the store.
+// Its contents are lost when the process exits.
 type LocalStorage struct {
-	m map[string]*Sale
+	mu      sync.RWMutex
+	m       map[string]*Sale
+	history map[string][]StateChange
+	outbox  []Event // pendientes, en orden de Seq
+	seq     int64
 }
 
-// NewLocalStorage instantiates a new LocalStorage with an empty map.
+// NewLocalStorage instantiates a new LocalStorage with empty maps.
 func NewLocalStorage() *LocalStorage {
 	return &LocalStorage{
-		m: map[string]*Sale{},
+		m:       map[string]*Sale{},
+		history: map[string][]StateChange{},
 	}
 }
 
-// Set stores or updates a sale in the local storage.
+// Set stores or updates a sale in the local storage, appending events to
+// the outbox.
 // Returns ErrEmptyID if the sale has an empty ID.
-func (l *LocalStorage) Set(sale *Sale) error {
+func (l *LocalStorage) Set(sale *Sale, events ...Event) error {
 	if sale.ID == "" {
 		return ErrEmptyID
 	}
 
-	l.m[sale.ID] = sale
+	stored := copySale(sale)
+	l.mu.Lock()
+	l.m[sale.ID] = &stored
+	l.appendEvents(events)
+	l.mu.Unlock()
 	return nil
 }
 
+// SetAll stores or updates several sales in the local storage at once.
+// Returns ErrEmptyID, storing nothing, if any sale has an empty ID.
+func (l *LocalStorage) SetAll(sales []*Sale, events ...Event) error {
+	stored := make([]Sale, len(sales))
+	for i, sale := range sales {
+		if sale.ID == "" {
+			return ErrEmptyID
+		}
+		stored[i] = copySale(sale)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range stored {
+		l.m[stored[i].ID] = &stored[i]
+	}
+	l.appendEvents(events)
+	return nil
+}
+
+// CompareAndSet replaces a sale in the local storage only if the stored
+// version equals version, recording change in its history when non-nil
+// and appending events to the outbox.
+// Returns ErrNotFound if the sale does not exist, or ErrVersionConflict if
+// it was modified in the meantime.
+func (l *LocalStorage) CompareAndSet(sale *Sale, version int, change *StateChange, events ...Event) error {
+	if sale.ID == "" {
+		return ErrEmptyID
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.m[sale.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Version != version {
+		return ErrVersionConflict
+	}
+
+	stored := copySale(sale)
+	l.m[sale.ID] = &stored
+	if change != nil {
+		l.history[sale.ID] = append(l.history[sale.ID], *change)
+	}
+	l.appendEvents(events)
+	return nil
+}
+
+// appendEvents assigns Seq to events and queues them. mu must be held.
+func (l *LocalStorage) appendEvents(events []Event) {
+	for _, e := range events {
+		l.seq++
+		e.Seq = l.seq
+		l.outbox = append(l.outbox, e)
+	}
+}
+
+// PendingEvents returns up to limit events not yet marked as dispatched.
+func (l *LocalStorage) PendingEvents(limit int) ([]Event, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	n := min(limit, len(l.outbox))
+	return append([]Event{}, l.outbox[:n]...), nil
+}
+
+// MarkDispatched drops events from the pending outbox.
+// Dispatched events are not kept in memory.
+func (l *LocalStorage) MarkDispatched(seqs ...int64) error {
+	done := make(map[int64]bool, len(seqs))
+	for _, seq := range seqs {
+		done[seq] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending := l.outbox[:0]
+	for _, e := range l.outbox {
+		if !done[e.Seq] {
+			pending = append(pending, e)
+		}
+	}
+	l.outbox = pending
+	return nil
+}
+
+// History returns the state changes recorded for a sale, oldest first.
+// Returns ErrNotFound if the sale does not exist.
+func (l *LocalStorage) History(id string) ([]StateChange, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if _, ok := l.m[id]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]StateChange{}, l.history[id]...), nil
+}
+
 // Read retrieves a sale from the local storage by ID.
 // Returns ErrNotFound if the sale is not found.
 func (l *LocalStorage) Read(id string) (*Sale, error) {
+	l.mu.RLock()
 	u, ok := l.m[id]
+	l.mu.RUnlock()
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	return u, nil
+	found := copySale(u)
+	return &found, nil
 }
 
-// Delete removes a sale from the local storage by ID.
+// Delete removes a sale and its history from the local storage by ID.
 // Returns ErrNotFound if the sale does not exist.
 func (l *LocalStorage) Delete(id string) error {
-	_, err := l.Read(id)
-	if err != nil {
-		return err
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.m[id]; !ok {
+		return ErrNotFound
 	}
 
 	delete(l.m, id)
+	delete(l.history, id)
 	return nil
 }
 
 // Crear endpoint GET /sales con filtros por user_id y status.
 // GetAll returns a slice of all Sale objects in storage.
-func (ls *LocalStorage) GetAll() []Sale {
+func (ls *LocalStorage) GetAll() ([]Sale, error) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
 
 	sales := make([]Sale, 0, len(ls.m))
 	for _, sale := range ls.m {
-		sales = append(sales, *sale)
+		sales = append(sales, copySale(sale))
+	}
+	return sales, nil
+}
+
+// Scan calls fn for every sale in the local storage.
+// Stored sales are never modified in place, so only the pointers are
+// snapshotted under the lock and fn runs without holding it.
+func (ls *LocalStorage) Scan(fn func(Sale) error) error {
+	ls.mu.RLock()
+	snapshot := make([]*Sale, 0, len(ls.m))
+	for _, sale := range ls.m {
+		snapshot = append(snapshot, sale)
+	}
+	ls.mu.RUnlock()
+
+	for _, sale := range snapshot {
+		if err := fn(copySale(sale)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping always succeeds: the local storage lives in memory.
+func (ls *LocalStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// copySale returns a copy of sale that shares no memory with it,
+// DeletedAt included.
+func copySale(sale *Sale) Sale {
+	c := *sale
+	if sale.DeletedAt != nil {
+		deletedAt := *sale.DeletedAt
+		c.DeletedAt = &deletedAt
 	}
-	return sales
+	return c
 }
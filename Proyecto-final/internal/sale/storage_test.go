@@ -0,0 +1,112 @@
+package sale
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_ReturnsCopies(t *testing.T) {
+	s := NewLocalStorage()
+	in := &Sale{ID: "s1", UserID: "u1", Estado: "pending"}
+	require.NoError(t, s.Set(in))
+
+	// modificar el valor original no debe afectar lo guardado
+	in.Estado = "approved"
+	got, err := s.Read("s1")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.Estado)
+
+	// ni modificar lo leído
+	got.Estado = "rejected"
+	again, err := s.Read("s1")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", again.Estado)
+
+	// DeletedAt es un puntero: tampoco se comparte al guardar ni al leer
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mine := deletedAt
+	in = &Sale{ID: "s2", DeletedAt: &mine}
+	require.NoError(t, s.Set(in))
+	*in.DeletedAt = deletedAt.Add(time.Hour)
+	got, err = s.Read("s2")
+	require.NoError(t, err)
+	assert.Equal(t, deletedAt, *got.DeletedAt)
+
+	*got.DeletedAt = deletedAt.Add(2 * time.Hour)
+	all, err := s.GetAll()
+	require.NoError(t, err)
+	for _, sale := range all {
+		if sale.ID == "s2" {
+			assert.Equal(t, deletedAt, *sale.DeletedAt)
+		}
+	}
+}
+
+func TestService_Export(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+	for i := range 5 {
+		require.NoError(t, svc.Create(&Sale{UserID: fmt.Sprintf("u%d", i%2), Amount: NewMoney(100, DefaultCurrency)}))
+	}
+
+	var users []string
+	require.NoError(t, svc.Export(Filter{UserID: "u1"}, func(s Sale) error {
+		users = append(users, s.UserID)
+		// escribir durante el recorrido no debe bloquear
+		return svc.storage.Set(&Sale{ID: "extra-" + s.ID, UserID: "u9"})
+	}))
+	assert.Equal(t, []string{"u1", "u1"}, users)
+
+	called := false
+	err := svc.Export(Filter{MinAmount: &Money{Minor: 10}, MaxAmount: &Money{Minor: 1}}, func(Sale) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	assert.False(t, called)
+}
+
+// Correr con -race: crea, actualiza y lista ventas en paralelo.
+func TestService_ConcurrentAccess(t *testing.T) {
+	svc := NewService(NewLocalStorage())
+
+	const workers = 16
+	const perWorker = 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%d", w%4)
+			for i := 0; i < perWorker; i++ {
+				s := &Sale{UserID: userID, Amount: NewMoney(int64(i+1), DefaultCurrency), Estado: "pending"}
+				if err := svc.Create(s); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := svc.Update(s.ID, &UpdateFields{Estado: "approved"}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := svc.Find(Filter{UserID: userID}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	for u := 0; u < 4; u++ {
+		sales, err := svc.Find(Filter{UserID: fmt.Sprintf("user-%d", u), Status: "approved"})
+		require.NoError(t, err)
+		total += len(sales)
+	}
+	assert.Equal(t, workers*perWorker, total)
+}
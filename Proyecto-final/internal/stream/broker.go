@@ -0,0 +1,179 @@
+// Package stream fans sale domain events out to live subscribers, such as
+// the Server-Sent Events clients of GET /sales/stream.
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"sales-api/internal/sale"
+)
+
+// Default broker settings.
+const (
+	// DefaultReplaySize is how many recent events are kept to resume
+	// subscriptions.
+	DefaultReplaySize = 1000
+	// DefaultSubscriberBuffer is how many events a subscriber may fall
+	// behind before it is dropped.
+	DefaultSubscriberBuffer = 64
+)
+
+// Broker receives events from a sale.Dispatcher (see Publish) and hands
+// them to its subscribers as they arrive.
+//
+// It keeps the latest events in memory so a subscriber that reconnects can
+// resume after the last event it saw, identified by its Seq. A subscriber
+// that does not keep up is dropped: its channel is closed and it is
+// expected to resume.
+type Broker struct {
+	replaySize int
+	bufferSize int
+
+	mu     sync.Mutex
+	replay []sale.Event // últimos eventos, en orden de Seq
+	last   int64        // Seq del último evento publicado
+	subs   map[*Subscription]struct{}
+}
+
+// Option configures optional Broker behaviour.
+type Option func(*Broker)
+
+// WithReplaySize sets how many recent events are kept for resuming.
+func WithReplaySize(n int) Option {
+	return func(b *Broker) {
+		b.replaySize = n
+	}
+}
+
+// WithSubscriberBuffer sets how many events a subscriber may fall behind
+// before it is dropped.
+func WithSubscriberBuffer(n int) Option {
+	return func(b *Broker) {
+		b.bufferSize = n
+	}
+}
+
+// NewBroker creates a Broker without subscribers.
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		replaySize: DefaultReplaySize,
+		bufferSize: DefaultSubscriberBuffer,
+		subs:       map[*Subscription]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Subscription receives the events accepted by its match function.
+type Subscription struct {
+	broker *Broker
+	match  func(sale.Event) bool
+	ch     chan sale.Event
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscription is closed or dropped for falling behind.
+func (s *Subscription) Events() <-chan sale.Event {
+	return s.ch
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.drop(s)
+}
+
+// Subscribe returns a subscription to the events published from now on
+// that match accepts; a nil match accepts every event.
+func (b *Broker) Subscribe(match func(sale.Event) bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(match)
+}
+
+// Resume is like Subscribe, but first returns the matching events
+// published after the one with Seq after. complete is false when some of
+// those events are no longer kept (or were never seen, e.g. after a
+// restart), so the subscriber should reload its state.
+func (b *Broker) Resume(after int64, match func(sale.Event) bool) (sub *Subscription, missed []sale.Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = b.subscribe(match)
+	complete = after == b.last ||
+		(after < b.last && len(b.replay) > 0 && b.replay[0].Seq <= after+1)
+	for _, e := range b.replay {
+		if e.Seq > after && sub.match(e) {
+			missed = append(missed, e)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Close ends every subscription, e.g. so streaming responses finish when
+// the server shuts down. Later subscriptions are not affected.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// subscribe registers a new subscription. mu must be held.
+func (b *Broker) subscribe(match func(sale.Event) bool) *Subscription {
+	if match == nil {
+		match = func(sale.Event) bool { return true }
+	}
+	sub := &Subscription{broker: b, match: match, ch: make(chan sale.Event, b.bufferSize)}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// drop removes sub and closes its channel. mu must be held.
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+}
+
+// Publish hands e to every matching subscriber. It implements
+// sale.Subscriber; events already seen (by Seq) are ignored, since the
+// dispatcher may deliver them more than once. It never blocks.
+func (b *Broker) Publish(ctx context.Context, e sale.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.Seq <= b.last {
+		return nil
+	}
+	b.last = e.Seq
+	b.replay = append(b.replay, e)
+	if len(b.replay) > b.replaySize {
+		// append realoca cuando se acaba la capacidad, así que lo
+		// descartado no se acumula
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// se quedó atrás: que se reconecte y retome desde su último evento
+			b.drop(sub)
+		}
+	}
+	return nil
+}
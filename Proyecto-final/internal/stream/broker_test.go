@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"sales-api/internal/sale"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(seq int64, userID string) sale.Event {
+	return sale.Event{Seq: seq, Type: sale.SaleCreated, Sale: sale.Sale{UserID: userID}}
+}
+
+func publish(t *testing.T, b *Broker, events ...sale.Event) {
+	t.Helper()
+	for _, e := range events {
+		require.NoError(t, b.Publish(context.Background(), e))
+	}
+}
+
+// drain returns the Seq of the events waiting on sub.
+func drain(sub *Subscription) []int64 {
+	var seqs []int64
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				return seqs
+			}
+			seqs = append(seqs, e.Seq)
+		default:
+			return seqs
+		}
+	}
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	b := NewBroker()
+	publish(t, b, event(1, "u1"))
+
+	all := b.Subscribe(nil)
+	u2 := b.Subscribe(func(e sale.Event) bool { return e.Sale.UserID == "u2" })
+
+	// duplicado del dispatcher: se ignora
+	publish(t, b, event(2, "u1"), event(3, "u2"), event(3, "u2"))
+
+	assert.Equal(t, []int64{2, 3}, drain(all))
+	assert.Equal(t, []int64{3}, drain(u2))
+
+	u2.Close()
+	u2.Close()
+	_, open := <-u2.Events()
+	assert.False(t, open)
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker(WithReplaySize(3))
+	publish(t, b, event(1, "u1"), event(2, "u2"), event(3, "u1"), event(4, "u1"))
+
+	t.Run("retoma después del último visto", func(t *testing.T) {
+		sub, missed, complete := b.Resume(2, func(e sale.Event) bool { return e.Sale.UserID == "u1" })
+		defer sub.Close()
+		assert.True(t, complete)
+		require.Len(t, missed, 2)
+		assert.Equal(t, int64(3), missed[0].Seq)
+		assert.Equal(t, int64(4), missed[1].Seq)
+
+		publish(t, b, event(5, "u1"))
+		assert.Equal(t, []int64{5}, drain(sub))
+	})
+
+	t.Run("al día", func(t *testing.T) {
+		sub, missed, complete := b.Resume(5, nil)
+		defer sub.Close()
+		assert.True(t, complete)
+		assert.Empty(t, missed)
+	})
+
+	t.Run("eventos descartados", func(t *testing.T) {
+		sub, missed, complete := b.Resume(1, nil)
+		defer sub.Close()
+		assert.False(t, complete)
+		assert.Len(t, missed, 3)
+	})
+
+	t.Run("id desconocido", func(t *testing.T) {
+		sub, missed, complete := b.Resume(99, nil)
+		defer sub.Close()
+		assert.False(t, complete)
+		assert.Empty(t, missed)
+	})
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(WithSubscriberBuffer(2))
+	slow := b.Subscribe(nil)
+
+	publish(t, b, event(1, "u1"), event(2, "u1"), event(3, "u1"))
+
+	// recibe lo que alcanzó a encolar y después el canal se cierra
+	assert.Equal(t, []int64{1, 2}, drain(slow))
+	_, open := <-slow.Events()
+	assert.False(t, open)
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker()
+	a, c := b.Subscribe(nil), b.Subscribe(nil)
+
+	b.Close()
+	_, open := <-a.Events()
+	assert.False(t, open)
+	_, open = <-c.Events()
+	assert.False(t, open)
+	a.Close()
+
+	// las suscripciones posteriores siguen funcionando
+	later := b.Subscribe(nil)
+	publish(t, b, event(1, "u1"))
+	assert.Equal(t, []int64{1}, drain(later))
+}
@@ -0,0 +1,48 @@
+package usersclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_HalfOpenAllowsSingleProbe(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow(), "only one probe while half-open")
+
+	// la prueba falla: vuelve a abrirse por otro cooldown completo
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	now = now.Add(30 * time.Second)
+	assert.False(t, b.Allow())
+}
+
+func TestBreaker_IgnoreReleasesProbe(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Allow())
+	b.Failure()
+	now = now.Add(time.Minute)
+
+	// una prueba cancelada no decide nada: la siguiente llamada prueba
+	assert.True(t, b.Allow())
+	b.Ignore()
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, StateClosed, b.State())
+}
@@ -0,0 +1,181 @@
+package usersclient
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default cache settings.
+const (
+	DefaultCacheSize   = 1000
+	DefaultCacheTTL    = time.Minute
+	DefaultNegativeTTL = 5 * time.Second
+)
+
+// CacheStats is a snapshot of a CachedClient's counters.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Evictions counts entries dropped to make room, not expired or
+	// invalidated ones.
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// HitRatio returns hits / (hits + misses), or 0 before the first lookup.
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// CachedClient is a Client that remembers recent lookups of another Client.
+//
+// Found users are kept for ttl and missing users for negativeTTL, so a user
+// created right after a failed lookup is picked up quickly. Errors other
+// than ErrNotFound are never cached. When the cache is full the least
+// recently used entry is evicted.
+type CachedClient struct {
+	next        Client
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List // front = most recently used
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type cacheEntry struct {
+	id      string
+	user    *User // nil means "not found"
+	expires time.Time
+}
+
+// NewCachedClient wraps next with an LRU cache holding up to capacity users.
+// A zero negativeTTL disables caching of missing users.
+func NewCachedClient(next Client, capacity int, ttl, negativeTTL time.Duration) *CachedClient {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &CachedClient{
+		next:        next,
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// GetUser implements Client, answering from the cache when possible.
+func (c *CachedClient) GetUser(ctx context.Context, id string) (*User, error) {
+	if user, ok, found := c.lookup(id); ok {
+		if !found {
+			return nil, ErrNotFound
+		}
+		return user, nil
+	}
+
+	user, err := c.next.GetUser(ctx, id)
+	switch {
+	case err == nil:
+		c.store(id, user, c.ttl)
+	case errors.Is(err, ErrNotFound) && c.negativeTTL > 0:
+		c.store(id, nil, c.negativeTTL)
+	}
+
+	return user, err
+}
+
+// Invalidate drops the cached result for id, if any.
+func (c *CachedClient) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge drops every cached result. Counters are kept.
+func (c *CachedClient) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+// Stats returns the current hit/miss counters and size.
+func (c *CachedClient) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions, Entries: c.lru.Len()}
+}
+
+// lookup returns a copy of the cached user, whether the cache had a live
+// entry (ok) and whether that entry is a found user.
+func (c *CachedClient) lookup(id string) (user *User, ok, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, exists := c.entries[id]
+	if exists {
+		entry := el.Value.(*cacheEntry)
+		if c.now().Before(entry.expires) {
+			c.hits++
+			c.lru.MoveToFront(el)
+			if entry.user == nil {
+				return nil, true, false
+			}
+			u := *entry.user
+			return &u, true, true
+		}
+		c.remove(el)
+	}
+
+	c.misses++
+	return nil, false, false
+}
+
+func (c *CachedClient) store(id string, user *User, ttl time.Duration) {
+	var stored *User
+	if user != nil {
+		u := *user
+		stored = &u
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{id: id, user: stored, expires: c.now().Add(ttl)}
+	if el, ok := c.entries[id]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[id] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+// remove must be called with mu held.
+func (c *CachedClient) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).id)
+}
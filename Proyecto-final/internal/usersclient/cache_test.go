@@ -0,0 +1,95 @@
+package usersclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cachea usuarios encontrados hasta el TTL", func(t *testing.T) {
+		fake := NewFake(User{ID: "u1", Name: "ana"})
+		now := time.Now()
+		c := NewCachedClient(fake, 10, time.Minute, time.Second)
+		c.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			u, err := c.GetUser(ctx, "u1")
+			require.NoError(t, err)
+			assert.Equal(t, "ana", u.Name)
+		}
+		assert.Equal(t, 1, fake.Calls())
+
+		now = now.Add(time.Minute)
+		_, err := c.GetUser(ctx, "u1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, fake.Calls())
+
+		stats := c.Stats()
+		assert.Equal(t, uint64(2), stats.Hits)
+		assert.Equal(t, uint64(2), stats.Misses)
+		assert.InDelta(t, 0.5, stats.HitRatio(), 0.001)
+	})
+
+	t.Run("los negativos expiran antes", func(t *testing.T) {
+		fake := NewFake()
+		now := time.Now()
+		c := NewCachedClient(fake, 10, time.Minute, time.Second)
+		c.now = func() time.Time { return now }
+
+		_, err := c.GetUser(ctx, "nadie")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = c.GetUser(ctx, "nadie")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, 1, fake.Calls())
+
+		now = now.Add(2 * time.Second)
+		_, _ = c.GetUser(ctx, "nadie")
+		assert.Equal(t, 2, fake.Calls())
+	})
+
+	t.Run("no cachea errores de disponibilidad", func(t *testing.T) {
+		fake := NewFake(User{ID: "u1"})
+		fake.Err = ErrUnavailable
+		c := NewCachedClient(fake, 10, time.Minute, time.Second)
+
+		_, err := c.GetUser(ctx, "u1")
+		assert.ErrorIs(t, err, ErrUnavailable)
+
+		fake.Err = nil
+		_, err = c.GetUser(ctx, "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, fake.Calls())
+	})
+
+	t.Run("desaloja el menos usado y permite invalidar", func(t *testing.T) {
+		fake := NewFake(User{ID: "u1"}, User{ID: "u2"}, User{ID: "u3"})
+		c := NewCachedClient(fake, 2, time.Minute, time.Second)
+
+		_, _ = c.GetUser(ctx, "u1")
+		_, _ = c.GetUser(ctx, "u2")
+		_, _ = c.GetUser(ctx, "u1") // u2 pasa a ser el menos usado
+		_, _ = c.GetUser(ctx, "u3") // desaloja u2
+		assert.Equal(t, 3, fake.Calls())
+		assert.Equal(t, 2, c.Stats().Entries)
+
+		_, _ = c.GetUser(ctx, "u1")
+		assert.Equal(t, 3, fake.Calls())
+		_, _ = c.GetUser(ctx, "u2")
+		assert.Equal(t, 4, fake.Calls())
+
+		c.Invalidate("u2")
+		_, _ = c.GetUser(ctx, "u2")
+		assert.Equal(t, 5, fake.Calls())
+		// invalidar no cuenta como desalojo
+		assert.Equal(t, uint64(2), c.Stats().Evictions)
+
+		c.Purge()
+		assert.Equal(t, 0, c.Stats().Entries)
+	})
+}
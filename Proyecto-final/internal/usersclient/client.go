@@ -0,0 +1,220 @@
+// Package usersclient provides typed access to the users service, which
+// owns the users referenced by sales.
+package usersclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"platform/requestid"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+var (
+	// ErrNotFound is returned when the users service has no user with the given ID.
+	ErrNotFound = errors.New("el usuario no existe")
+	// ErrUnavailable is returned when the users service cannot be reached or
+	// answers with an unexpected status.
+	ErrUnavailable = errors.New("servicio de usuarios no disponible")
+	// ErrRejected is returned when the users service answers with a 4xx
+	// other than 404. The service is up, so it does not count against the
+	// circuit breaker.
+	ErrRejected = errors.New("el servicio de usuarios rechazó la consulta")
+)
+
+// User is the user resource as returned by the users service.
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	NickName  string    `json:"nickname"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// Client looks up users in the users service.
+type Client interface {
+	// GetUser returns the user with the given ID.
+	// Returns ErrNotFound if it does not exist, an error wrapping
+	// ErrUnavailable if the service could not answer, or one wrapping
+	// ErrRejected if it refused the request.
+	GetUser(ctx context.Context, id string) (*User, error)
+}
+
+// Default resilience settings used by New.
+const (
+	DefaultTimeout          = 2 * time.Second
+	DefaultRetries          = 2
+	DefaultBackoff          = 100 * time.Millisecond
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 10 * time.Second
+)
+
+// HTTPClient is a Client that calls the users service REST API.
+//
+// Each attempt is bounded by a timeout, network errors and 5xx answers are
+// retried with exponential backoff, and a Breaker fails calls fast while
+// the service keeps failing.
+type HTTPClient struct {
+	client  *resty.Client
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	breaker *Breaker
+}
+
+// Option configures an HTTPClient.
+type Option func(*HTTPClient)
+
+// WithTimeout bounds each individual attempt.
+func WithTimeout(d time.Duration) Option {
+	return func(c *HTTPClient) {
+		c.timeout = d
+	}
+}
+
+// WithRetries sets how many times a failed attempt is retried and the
+// wait before the first retry, which doubles on every subsequent one.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *HTTPClient) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithBreaker replaces the default circuit breaker.
+func WithBreaker(b *Breaker) Option {
+	return func(c *HTTPClient) {
+		c.breaker = b
+	}
+}
+
+// New creates an HTTPClient for the users service at baseURL,
+// e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) *HTTPClient {
+	c := &HTTPClient{
+		client:  resty.New().SetBaseURL(baseURL),
+		timeout: DefaultTimeout,
+		retries: DefaultRetries,
+		backoff: DefaultBackoff,
+		breaker: NewBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client.OnBeforeRequest(forwardRequestID)
+	return c
+}
+
+// forwardRequestID sends the ID of the request being served, if any, so
+// the users service logs can be correlated with ours.
+func forwardRequestID(_ *resty.Client, r *resty.Request) error {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		r.SetHeader(requestid.Header, id)
+	}
+	return nil
+}
+
+// BreakerState reports the state of the client's circuit breaker.
+func (c *HTTPClient) BreakerState() State {
+	return c.breaker.State()
+}
+
+// Ping checks that the users service answers GET /ping, with a single
+// attempt bounded by the client timeout. It does not go through the
+// breaker, so health probes neither trip it nor are rejected by it.
+func (c *HTTPClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.R().SetContext(ctx).Get("/ping")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("%w: respuesta inesperada %d", ErrUnavailable, resp.StatusCode())
+	}
+	return nil
+}
+
+// GetUser implements Client using GET /users/:id.
+// Returns ErrCircuitOpen without calling the service while the breaker is open.
+// If ctx is cancelled or expires it returns ctx's error, without retrying
+// and without counting the call against the breaker.
+func (c *HTTPClient) GetUser(ctx context.Context, id string) (*User, error) {
+	if id == "" {
+		return nil, ErrNotFound
+	}
+	if !c.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	user, err := c.getWithRetries(ctx, id)
+	switch {
+	case ctx.Err() != nil:
+		// lo canceló quien llama (p. ej. el cliente se desconectó): no dice
+		// nada del servicio
+		c.breaker.Ignore()
+	case errors.Is(err, ErrUnavailable):
+		c.breaker.Failure()
+	default:
+		// un 404 u otro 4xx también prueban que el servicio responde
+		c.breaker.Success()
+	}
+
+	return user, err
+}
+
+// getWithRetries performs up to retries+1 attempts, waiting between them.
+func (c *HTTPClient) getWithRetries(ctx context.Context, id string) (*User, error) {
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		user, retry, err := c.get(ctx, id)
+		if !retry || attempt >= c.retries {
+			return user, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// get performs a single attempt and reports whether it is worth retrying.
+func (c *HTTPClient) get(ctx context.Context, id string) (*User, bool, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.R().
+		SetContext(attemptCtx).
+		SetPathParam("id", id).
+		SetResult(&User{}).
+		Get("/users/{id}")
+	if err != nil {
+		// solo el timeout del intento es culpa del servicio
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		return nil, true, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	switch code := resp.StatusCode(); {
+	case code == http.StatusOK:
+		return resp.Result().(*User), false, nil
+	case code == http.StatusNotFound:
+		return nil, false, ErrNotFound
+	case code >= http.StatusInternalServerError:
+		return nil, true, fmt.Errorf("%w: respuesta %d", ErrUnavailable, code)
+	case code >= http.StatusBadRequest:
+		return nil, false, fmt.Errorf("%w: respuesta %d", ErrRejected, code)
+	default:
+		return nil, false, fmt.Errorf("%w: respuesta inesperada %d", ErrUnavailable, code)
+	}
+}
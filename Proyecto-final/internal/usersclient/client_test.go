@@ -0,0 +1,229 @@
+package usersclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"platform/requestid"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient_GetUser(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/abc123":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"abc123","name":"nico","version":2}`))
+		case "/users/boom":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+
+	u, err := c.GetUser(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "nico", u.Name)
+	assert.Equal(t, 2, u.Version)
+
+	_, err = c.GetUser(context.Background(), "no-existe")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = c.GetUser(context.Background(), "boom")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestHTTPClient_ForwardsRequestID(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(requestid.Header)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"id":"abc123","name":"nico"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(1, time.Millisecond))
+
+	// cada reintento lleva el mismo ID
+	_, err := c.GetUser(requestid.NewContext(context.Background(), "req-1"), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", <-received)
+	assert.Equal(t, "req-1", <-received)
+
+	// sin ID en el contexto no se inventa uno
+	_, err = c.GetUser(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, <-received)
+}
+
+func TestHTTPClient_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	_, err := New(srv.URL).GetUser(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestHTTPClient_RetriesServerErrors(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// falla dos veces y luego responde bien
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"abc123"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(2, time.Millisecond))
+
+	u, err := c.GetUser(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", u.ID)
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestHTTPClient_DoesNotRetryNotFound(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, WithRetries(3, time.Millisecond)).GetUser(context.Background(), "x")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(1), hits.Load())
+}
+
+func TestHTTPClient_RejectedIsNotUnavailable(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(3, time.Millisecond), WithBreaker(NewBreaker(1, time.Minute)))
+
+	_, err := c.GetUser(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(1), hits.Load())
+	// el servicio respondió: no abre el circuito
+	assert.Equal(t, StateClosed, c.BreakerState())
+}
+
+func TestHTTPClient_CallerCancellation(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(3, time.Millisecond), WithBreaker(NewBreaker(1, time.Minute)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := c.GetUser(ctx, "abc123")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+	// ni se reintenta ni cuenta como falla del servicio
+	assert.Equal(t, int32(1), hits.Load())
+	assert.Equal(t, StateClosed, c.BreakerState())
+}
+
+func TestHTTPClient_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithTimeout(20*time.Millisecond), WithRetries(1, time.Millisecond))
+
+	start := time.Now()
+	_, err := c.GetUser(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestHTTPClient_CircuitBreaker(t *testing.T) {
+	var hits atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"abc123"}`))
+	}))
+	defer srv.Close()
+
+	breaker := NewBreaker(2, 50*time.Millisecond)
+	c := New(srv.URL, WithRetries(0, 0), WithBreaker(breaker))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetUser(context.Background(), "abc123")
+		require.ErrorIs(t, err, ErrUnavailable)
+	}
+	require.Equal(t, StateOpen, c.BreakerState())
+
+	// abierto: falla rápido sin llegar al servidor
+	_, err := c.GetUser(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(2), hits.Load())
+
+	// pasado el cooldown, una prueba exitosa lo vuelve a cerrar
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, c.BreakerState())
+
+	_, err = c.GetUser(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, StateClosed, c.BreakerState())
+}
+
+func TestHTTPClient_Ping(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" || !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithBreaker(NewBreaker(1, time.Minute)))
+	require.NoError(t, c.Ping(context.Background()))
+
+	healthy.Store(false)
+	assert.ErrorIs(t, c.Ping(context.Background()), ErrUnavailable)
+	// los pings fallidos no abren el circuito
+	assert.Equal(t, StateClosed, c.BreakerState())
+
+	srv.Close()
+	assert.ErrorIs(t, c.Ping(context.Background()), ErrUnavailable)
+}
@@ -0,0 +1,50 @@
+package usersclient
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake is an in-memory Client for tests. It knows the users it was created
+// with and, when Err is set, fails every lookup with it instead.
+type Fake struct {
+	// Err, when non-nil, is returned by every GetUser call.
+	Err error
+
+	mu    sync.Mutex
+	users map[string]User
+	calls int
+}
+
+// NewFake returns a Fake that knows the given users.
+func NewFake(users ...User) *Fake {
+	f := &Fake{users: map[string]User{}}
+	for _, u := range users {
+		f.users[u.ID] = u
+	}
+	return f
+}
+
+// GetUser implements Client.
+func (f *Fake) GetUser(ctx context.Context, id string) (*User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	u, ok := f.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// Calls returns how many times GetUser has been called.
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"sales-api/internal/sale"
+)
+
+// Delivery statuses.
+const (
+	// StatusPending deliveries are waiting for their next attempt.
+	StatusPending = "pending"
+	// StatusSucceeded deliveries were accepted with a 2xx answer.
+	StatusSucceeded = "succeeded"
+	// StatusDead deliveries exhausted their attempts; they form the
+	// dead-letter list.
+	StatusDead = "dead"
+)
+
+// Headers sent with every delivery.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Delivery is the sending of one event to one webhook, across all its
+// attempts.
+type Delivery struct {
+	ID        string         `json:"id"`
+	WebhookID string         `json:"webhook_id"`
+	EventID   string         `json:"event_id"`
+	EventType sale.EventType `json:"event_type"`
+	Status    string         `json:"status"`
+	Attempts  int            `json:"attempts"`
+	// LastError describes why the last attempt failed, if it did.
+	LastError string `json:"last_error,omitempty"`
+	// ResponseStatus is the HTTP status of the last answer, 0 if there
+	// was none.
+	ResponseStatus int       `json:"response_status,omitempty"`
+	NextAttemptAt  time.Time `json:"next_attempt_at,omitzero"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at,omitzero"`
+
+	payload []byte
+}
+
+// attempt is a due delivery with the webhook it goes to.
+type attempt struct {
+	Delivery
+	url    string
+	secret string
+}
+
+// Run delivers pending deliveries until ctx is cancelled, checking for due
+// ones every poll interval and whenever Handle stores something. It does
+// not wait for attempts in flight when ctx is cancelled: those deliveries
+// stay pending and are sent again on the next Run. It returns ctx.Err().
+func (s *Service) Run(ctx context.Context) error {
+	for {
+		n, err := s.Deliver(ctx)
+		if err != nil {
+			s.onError(err)
+		}
+
+		// con un lote lleno puede haber más entregas vencidas: no esperar
+		wait := s.pollInterval
+		if n == DefaultBatch && err == nil {
+			wait = 0
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Deliver makes one attempt at up to DefaultBatch due deliveries and
+// returns how many were attempted, along with any error reading or
+// updating the store. Deliver must not be called concurrently with itself
+// or Run.
+func (s *Service) Deliver(ctx context.Context) (int, error) {
+	due, err := s.due()
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, a := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		status, err := s.send(ctx, a)
+		if ctx.Err() != nil {
+			// cortado por el apagado: sigue pendiente para el próximo arranque
+			break
+		}
+		errs = append(errs, s.record(a.Delivery, status, err))
+	}
+	return len(due), errors.Join(errs...)
+}
+
+// due returns the pending deliveries whose next attempt time has come.
+func (s *Service) due() ([]attempt, error) {
+	deliveries, err := s.store.DueDeliveries(s.now(), DefaultBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := map[string]*Webhook{}
+	due := make([]attempt, 0, len(deliveries))
+	for _, d := range deliveries {
+		w, ok := hooks[d.WebhookID]
+		if !ok {
+			if w, err = s.store.Webhook(d.WebhookID); err != nil {
+				return nil, fmt.Errorf("entrega %s: %w", d.ID, err)
+			}
+			hooks[d.WebhookID] = w
+		}
+		due = append(due, attempt{Delivery: d, url: w.URL, secret: w.Secret})
+	}
+	return due, nil
+}
+
+// send POSTs a delivery and returns the status of the answer.
+// Any answer other than 2xx is an error.
+func (s *Service) send(ctx context.Context, a attempt) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(SignatureHeader, Sign(a.secret, timestamp, a.payload)).
+		SetHeader(TimestampHeader, timestamp).
+		SetHeader(EventHeader, string(a.EventType)).
+		SetHeader(DeliveryHeader, a.ID).
+		SetBody(a.payload).
+		Post(a.url)
+	if err != nil {
+		return 0, err
+	}
+	if code := resp.StatusCode(); code < 200 || code > 299 {
+		return code, fmt.Errorf("respuesta inesperada %d", code)
+	}
+	return resp.StatusCode(), nil
+}
+
+// record stores the outcome of an attempt, scheduling a retry or moving
+// the delivery to the dead-letter list if it failed. Once it succeeds, the
+// oldest succeeded deliveries of the webhook beyond the retention are
+// removed.
+func (s *Service) record(d Delivery, status int, err error) error {
+	now := s.now()
+	d.Attempts++
+	d.ResponseStatus = status
+	d.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		d.Status = StatusSucceeded
+		d.LastError = ""
+		d.NextAttemptAt = time.Time{}
+	case d.Attempts >= s.maxAttempts:
+		d.Status = StatusDead
+		d.LastError = err.Error()
+		d.NextAttemptAt = time.Time{}
+	default:
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(s.retryWait(d.Attempts))
+	}
+
+	if err := s.store.UpdateDelivery(d); err != nil {
+		return fmt.Errorf("entrega %s: %w", d.ID, err)
+	}
+	if d.Status == StatusSucceeded {
+		return s.store.PruneSucceeded(d.WebhookID, s.retention)
+	}
+	return nil
+}
+
+// retryWait returns the wait after the given number of failed attempts:
+// backoff doubled on every attempt after the first, up to maxBackoff.
+func (s *Service) retryWait(attempts int) time.Duration {
+	wait := s.backoff
+	for i := 1; i < attempts && wait < s.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, s.maxBackoff)
+}
@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// migrations holds the schema changes applied by NewSQLiteStore, in order.
+// Entries must never be edited once released: append a new one instead.
+// The tables use IF NOT EXISTS because databases created before this
+// package had its own migrations already have them.
+var migrations = []string{
+	// 1-4: webhooks y sus entregas; viven en la base de ventas para
+	// sobrevivir reinicios junto con el outbox que los alimenta
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id         TEXT PRIMARY KEY,
+		url        TEXT NOT NULL,
+		events     TEXT NOT NULL,
+		secret     TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+		id              TEXT NOT NULL UNIQUE,
+		webhook_id      TEXT NOT NULL REFERENCES webhooks (id),
+		event_id        TEXT NOT NULL,
+		event_type      TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL,
+		last_error      TEXT NOT NULL,
+		response_status INTEGER NOT NULL,
+		next_attempt_at TEXT,
+		created_at      TEXT NOT NULL,
+		updated_at      TEXT,
+		UNIQUE (webhook_id, event_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status, webhook_id)`,
+}
+
+// SQLiteStore keeps webhooks and deliveries in the webhooks and
+// webhook_deliveries tables of a SQLite database, usually the sale one.
+// Pending deliveries survive a restart and are resumed by Run.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a store over db, as returned by
+// sale.SQLiteStorage.DB, and applies any pending schema migrations.
+// Closing db is up to the caller.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// migrate applies every migration newer than the version recorded in
+// webhook_schema_migrations, each one inside its own transaction. The
+// version is kept apart from the one of the sale tables, so both
+// packages can migrate the same database independently.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS webhook_schema_migrations (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("creating webhook_schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM webhook_schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("reading webhook schema version: %w", err)
+	}
+
+	for i := current; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying webhook migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO webhook_schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("recording webhook migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing webhook migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// SaveWebhook inserts a new webhook.
+func (s *SQLiteStore) SaveWebhook(w Webhook) error {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO webhooks (id, url, events, secret, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		w.ID, w.URL, string(events), w.Secret, formatTime(w.CreatedAt),
+	)
+	return err
+}
+
+// Webhook returns the webhook with the given ID.
+// Returns ErrNotFound if it does not exist.
+func (s *SQLiteStore) Webhook(id string) (*Webhook, error) {
+	w, err := scanWebhook(s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return w, err
+}
+
+// Webhooks returns every webhook, oldest first.
+func (s *SQLiteStore) Webhooks() ([]Webhook, error) {
+	rows, err := s.db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *w)
+	}
+	return hooks, rows.Err()
+}
+
+// AddDeliveries inserts the deliveries in one transaction, ignoring those
+// whose webhook and event already have one.
+func (s *SQLiteStore) AddDeliveries(ds ...Delivery) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range ds {
+		if _, err := tx.Exec(`
+			INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+			d.ID, d.WebhookID, d.EventID, string(d.EventType), string(d.payload), d.Status, d.Attempts,
+			d.LastError, d.ResponseStatus, formatZeroTime(d.NextAttemptAt), formatTime(d.CreatedAt), formatZeroTime(d.UpdatedAt),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateDelivery stores the outcome of an attempt.
+// Returns ErrNotFound if the delivery does not exist.
+func (s *SQLiteStore) UpdateDelivery(d Delivery) error {
+	res, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_error = ?, response_status = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, d.LastError, d.ResponseStatus, formatZeroTime(d.NextAttemptAt), formatZeroTime(d.UpdatedAt), d.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the deliveries of a webhook, oldest first.
+func (s *SQLiteStore) Deliveries(webhookID string) ([]Delivery, error) {
+	return s.queryDeliveries(`WHERE webhook_id = ? ORDER BY seq`, webhookID)
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now, oldest
+// first.
+func (s *SQLiteStore) DueDeliveries(now time.Time, limit int) ([]Delivery, error) {
+	return s.queryDeliveries(`WHERE status = ? AND next_attempt_at <= ? ORDER BY seq LIMIT ?`,
+		StatusPending, formatTime(now), limit)
+}
+
+// DeadLetters returns every dead delivery, oldest first.
+func (s *SQLiteStore) DeadLetters() ([]Delivery, error) {
+	return s.queryDeliveries(`WHERE status = ? ORDER BY seq`, StatusDead)
+}
+
+// PruneSucceeded deletes the succeeded deliveries of a webhook but the
+// newest keep.
+func (s *SQLiteStore) PruneSucceeded(webhookID string, keep int) error {
+	_, err := s.db.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE webhook_id = ? AND status = ? AND seq NOT IN (
+			SELECT seq FROM webhook_deliveries
+			WHERE webhook_id = ? AND status = ?
+			ORDER BY seq DESC
+			LIMIT ?
+		)`,
+		webhookID, StatusSucceeded, webhookID, StatusSucceeded, keep,
+	)
+	return err
+}
+
+func (s *SQLiteStore) queryDeliveries(where string, args ...any) ([]Delivery, error) {
+	rows, err := s.db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// webhookColumns lists the columns read by scanWebhook, in order.
+const webhookColumns = "id, url, events, secret, created_at"
+
+func scanWebhook(sc scanner) (*Webhook, error) {
+	var (
+		w                 Webhook
+		events, createdAt string
+	)
+	if err := sc.Scan(&w.ID, &w.URL, &events, &w.Secret, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, err
+	}
+
+	var err error
+	w.CreatedAt, err = parseTime(createdAt)
+	return &w, err
+}
+
+// deliveryColumns lists the columns read by scanDelivery and written by
+// AddDeliveries, in order.
+const deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, last_error, response_status, next_attempt_at, created_at, updated_at"
+
+func scanDelivery(sc scanner) (*Delivery, error) {
+	var (
+		d                        Delivery
+		payload, createdAt       string
+		nextAttemptAt, updatedAt sql.NullString
+	)
+	if err := sc.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.LastError, &d.ResponseStatus, &nextAttemptAt, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	d.payload = []byte(payload)
+
+	var err error
+	if d.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if d.NextAttemptAt, err = parseNullTime(nextAttemptAt); err != nil {
+		return nil, err
+	}
+	if d.UpdatedAt, err = parseNullTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// timeLayout is RFC 3339 with a fixed number of decimals, so timestamps
+// stored in UTC compare as text in the same order as in time.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatZeroTime stores a zero time as NULL.
+func formatZeroTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return formatTime(t)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parseNullTime reads NULL as the zero time.
+func parseNullTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+	return parseTime(s.String)
+}
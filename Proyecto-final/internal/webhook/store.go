@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store is the persistence contract used by Service. Implementations must
+// return ErrNotFound for unknown webhook IDs.
+type Store interface {
+	// SaveWebhook stores a new webhook, secret included.
+	SaveWebhook(w Webhook) error
+	// Webhook returns the webhook with the given ID, secret included.
+	Webhook(id string) (*Webhook, error)
+	// Webhooks returns every webhook, secrets included.
+	Webhooks() ([]Webhook, error)
+	// AddDeliveries stores new deliveries atomically. A delivery for a
+	// webhook and event that already have one is skipped, so handling an
+	// event twice does not send it twice.
+	AddDeliveries(ds ...Delivery) error
+	// UpdateDelivery stores the outcome of an attempt at a delivery.
+	UpdateDelivery(d Delivery) error
+	// Deliveries returns the deliveries of a webhook, oldest first.
+	Deliveries(webhookID string) ([]Delivery, error)
+	// DueDeliveries returns up to limit pending deliveries whose next
+	// attempt is not after now, oldest first.
+	DueDeliveries(now time.Time, limit int) ([]Delivery, error)
+	// DeadLetters returns every dead delivery, oldest first.
+	DeadLetters() ([]Delivery, error)
+	// PruneSucceeded removes the succeeded deliveries of a webhook but the
+	// newest keep.
+	PruneSucceeded(webhookID string, keep int) error
+}
+
+// MemoryStore is an in-memory Store. It is safe for concurrent use.
+// Its contents are lost when the process exits, so it only fits a
+// service whose sale outbox is in memory as well.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	hooks      map[string]Webhook
+	deliveries []Delivery // en orden de creación
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hooks: map[string]Webhook{}}
+}
+
+// SaveWebhook stores a new webhook.
+func (m *MemoryStore) SaveWebhook(w Webhook) error {
+	w.Events = slices.Clone(w.Events)
+
+	m.mu.Lock()
+	m.hooks[w.ID] = w
+	m.mu.Unlock()
+	return nil
+}
+
+// Webhook returns the webhook with the given ID.
+// Returns ErrNotFound if it does not exist.
+func (m *MemoryStore) Webhook(id string) (*Webhook, error) {
+	m.mu.RLock()
+	w, ok := m.hooks[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	w.Events = slices.Clone(w.Events)
+	return &w, nil
+}
+
+// Webhooks returns every webhook, in no particular order.
+func (m *MemoryStore) Webhooks() ([]Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hooks := make([]Webhook, 0, len(m.hooks))
+	for _, w := range m.hooks {
+		w.Events = slices.Clone(w.Events)
+		hooks = append(hooks, w)
+	}
+	return hooks, nil
+}
+
+// AddDeliveries appends the deliveries that are not stored yet.
+func (m *MemoryStore) AddDeliveries(ds ...Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range ds {
+		exists := slices.ContainsFunc(m.deliveries, func(s Delivery) bool {
+			return s.WebhookID == d.WebhookID && s.EventID == d.EventID
+		})
+		if !exists {
+			m.deliveries = append(m.deliveries, d)
+		}
+	}
+	return nil
+}
+
+// UpdateDelivery replaces the stored delivery with the same ID.
+// Returns ErrNotFound if there is none.
+func (m *MemoryStore) UpdateDelivery(d Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.deliveries, func(s Delivery) bool { return s.ID == d.ID })
+	if i < 0 {
+		return ErrNotFound
+	}
+	m.deliveries[i] = d
+	return nil
+}
+
+// Deliveries returns the deliveries of a webhook, oldest first.
+func (m *MemoryStore) Deliveries(webhookID string) ([]Delivery, error) {
+	return m.filter(-1, func(d Delivery) bool { return d.WebhookID == webhookID }), nil
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now.
+func (m *MemoryStore) DueDeliveries(now time.Time, limit int) ([]Delivery, error) {
+	return m.filter(limit, func(d Delivery) bool {
+		return d.Status == StatusPending && !d.NextAttemptAt.After(now)
+	}), nil
+}
+
+// DeadLetters returns every dead delivery, oldest first.
+func (m *MemoryStore) DeadLetters() ([]Delivery, error) {
+	return m.filter(-1, func(d Delivery) bool { return d.Status == StatusDead }), nil
+}
+
+// filter returns up to limit deliveries matching keep, in order; a
+// negative limit returns all of them.
+func (m *MemoryStore) filter(limit int, keep func(Delivery) bool) []Delivery {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := []Delivery{}
+	for _, d := range m.deliveries {
+		if len(out) == limit {
+			break
+		}
+		if keep(d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// PruneSucceeded drops the oldest succeeded deliveries of a webhook until
+// only keep are left.
+func (m *MemoryStore) PruneSucceeded(webhookID string, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	succeeded := 0
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID && d.Status == StatusSucceeded {
+			succeeded++
+		}
+	}
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d Delivery) bool {
+		if succeeded <= keep || d.WebhookID != webhookID || d.Status != StatusSucceeded {
+			return false
+		}
+		succeeded--
+		return true
+	})
+	return nil
+}
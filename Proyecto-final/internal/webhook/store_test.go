@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sales-api/internal/sale"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSQLiteStore returns a store over a fresh sale database at path.
+func newTestSQLiteStore(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	storage, err := sale.NewSQLiteStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	store, err := NewSQLiteStore(storage.DB())
+	require.NoError(t, err)
+	return store
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			return newTestSQLiteStore(t, filepath.Join(t.TempDir(), "sales.db"))
+		},
+	}
+
+	// con fracción de segundo, para comprobar que el orden no depende del formato
+	now := time.Date(2025, 1, 1, 12, 0, 0, 500_000_000, time.UTC)
+	delivery := func(id, event string, next time.Time) Delivery {
+		return Delivery{
+			ID: id, WebhookID: "w1", EventID: event, EventType: sale.SaleCreated,
+			Status: StatusPending, CreatedAt: now, NextAttemptAt: next, payload: []byte(`{"id":"` + event + `"}`),
+		}
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			st := newStore(t)
+
+			w := Webhook{ID: "w1", URL: "https://example.com", Events: []sale.EventType{sale.SaleCreated}, Secret: "s", CreatedAt: now}
+			require.NoError(t, st.SaveWebhook(w))
+			found, err := st.Webhook("w1")
+			require.NoError(t, err)
+			assert.Equal(t, w, *found)
+			_, err = st.Webhook("nope")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, st.AddDeliveries(
+				delivery("d1", "e1", now),
+				delivery("d2", "e2", now.Add(time.Second)),
+				delivery("d3", "e3", now.Add(-time.Second)),
+			))
+			// el mismo evento para el mismo webhook no se duplica
+			require.NoError(t, st.AddDeliveries(delivery("d4", "e1", now)))
+
+			all, err := st.Deliveries("w1")
+			require.NoError(t, err)
+			require.Len(t, all, 3)
+			assert.Equal(t, []byte(`{"id":"e1"}`), all[0].payload)
+
+			due, err := st.DueDeliveries(now, 10)
+			require.NoError(t, err)
+			require.Len(t, due, 2)
+			assert.Equal(t, "d1", due[0].ID)
+			assert.Equal(t, "d3", due[1].ID)
+
+			dead := all[0]
+			dead.Status, dead.Attempts, dead.LastError, dead.NextAttemptAt, dead.UpdatedAt = StatusDead, 6, "boom", time.Time{}, now
+			require.NoError(t, st.UpdateDelivery(dead))
+			letters, err := st.DeadLetters()
+			require.NoError(t, err)
+			require.Len(t, letters, 1)
+			assert.Equal(t, dead, letters[0])
+			assert.ErrorIs(t, st.UpdateDelivery(delivery("nope", "e9", now)), ErrNotFound)
+
+			for _, d := range all[1:] {
+				d.Status, d.NextAttemptAt = StatusSucceeded, time.Time{}
+				require.NoError(t, st.UpdateDelivery(d))
+			}
+			require.NoError(t, st.PruneSucceeded("w1", 1))
+			all, err = st.Deliveries("w1")
+			require.NoError(t, err)
+			// queda la muerta y la exitosa más nueva
+			require.Len(t, all, 2)
+			assert.Equal(t, "d1", all[0].ID)
+			assert.Equal(t, "d3", all[1].ID)
+		})
+	}
+}
+
+func TestSQLiteStore_MigratesExistingTables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.db")
+	storage, err := sale.NewSQLiteStorage(path)
+	require.NoError(t, err)
+	defer storage.Close()
+	db := storage.DB()
+
+	// base creada cuando las tablas venían de las migraciones de ventas
+	for _, m := range migrations {
+		_, err = db.Exec(m)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`INSERT INTO webhooks VALUES ('w1', 'https://example.com', '[]', 's', ?)`, formatTime(time.Now()))
+	require.NoError(t, err)
+
+	st, err := NewSQLiteStore(db)
+	require.NoError(t, err)
+	_, err = st.Webhook("w1")
+	assert.NoError(t, err)
+
+	// abrirla de nuevo no vuelve a migrar
+	_, err = NewSQLiteStore(db)
+	require.NoError(t, err)
+	var version int
+	require.NoError(t, db.QueryRow(`SELECT MAX(version) FROM webhook_schema_migrations`).Scan(&version))
+	assert.Equal(t, len(migrations), version)
+}
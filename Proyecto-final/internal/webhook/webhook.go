@@ -0,0 +1,353 @@
+// Package webhook notifies external systems of sale domain events by
+// POSTing signed JSON payloads to the URLs they register.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"sales-api/internal/sale"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when no webhook has the given ID.
+	ErrNotFound = errors.New("webhook no encontrado")
+	// ErrInvalidWebhook is returned when registering a webhook with an
+	// invalid URL or event type.
+	ErrInvalidWebhook = errors.New("webhook inválido")
+)
+
+// Events a webhook can subscribe to.
+var events = []sale.EventType{sale.SaleCreated, sale.SaleApproved, sale.SaleRejected}
+
+// Webhook is a registered callback URL.
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events filters which events are sent; empty means all of them.
+	Events []sale.EventType `json:"events"`
+	// Secret signs every delivery (see Sign). It is only shown once, in
+	// the response to the registration.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// wants reports whether the webhook subscribed to events of type t.
+func (w *Webhook) wants(t sale.EventType) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, t)
+}
+
+// Default delivery settings.
+const (
+	DefaultMaxAttempts  = 6
+	DefaultBackoff      = time.Second
+	DefaultMaxBackoff   = 5 * time.Minute
+	DefaultTimeout      = 5 * time.Second
+	DefaultPollInterval = time.Second
+	// DefaultRetention is how many succeeded deliveries are kept per
+	// webhook; pending and dead ones are always kept.
+	DefaultRetention = 100
+	// DefaultBatch is how many due deliveries Deliver attempts at most.
+	DefaultBatch = 100
+)
+
+// Service keeps the registered webhooks and delivers events to them.
+//
+// Events are stored as pending deliveries by Handle, which is meant to be
+// subscribed to a sale.Dispatcher, and sent by Run. A delivery that fails
+// (network error or non-2xx answer) is retried with exponential backoff;
+// after maxAttempts it is moved to the dead-letter list.
+//
+// Webhooks and deliveries live in a Store. Handle only returns once the
+// deliveries are stored, and a delivery stays pending until its outcome is
+// recorded, so with a durable Store nothing is lost if the process stops:
+// Run resumes the pending deliveries on the next start. A delivery
+// interrupted mid-attempt is sent again, so receivers must tolerate
+// duplicates (see DeliveryHeader).
+type Service struct {
+	store        Store
+	client       *resty.Client
+	privateHosts bool
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	timeout      time.Duration
+	pollInterval time.Duration
+	retention    int
+	onError      func(err error)
+	now          func() time.Time
+
+	wake chan struct{}
+}
+
+// Option configures optional Service behaviour.
+type Option func(*Service)
+
+// WithMaxAttempts sets how many times a delivery is attempted before it
+// is dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(s *Service) {
+		s.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the wait before the first retry, which doubles on
+// every subsequent one up to max.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(s *Service) {
+		s.backoff = initial
+		s.maxBackoff = max
+	}
+}
+
+// WithTimeout bounds each delivery attempt.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.timeout = d
+	}
+}
+
+// WithPollInterval sets how often Run looks for deliveries due for retry.
+func WithPollInterval(d time.Duration) Option {
+	return func(s *Service) {
+		s.pollInterval = d
+	}
+}
+
+// WithRetention sets how many succeeded deliveries are kept per webhook;
+// older ones are removed as new ones succeed.
+func WithRetention(n int) Option {
+	return func(s *Service) {
+		s.retention = n
+	}
+}
+
+// WithErrorHandler sets a function called when Run cannot read or update
+// the store, e.g. to log it. Run keeps going and tries again later.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(s *Service) {
+		s.onError = fn
+	}
+}
+
+// WithPrivateHosts lets webhooks point to loopback, link-local and
+// private addresses, which are refused by default so the service cannot
+// be used to reach the internal network. Meant for tests and local
+// development.
+func WithPrivateHosts(allow bool) Option {
+	return func(s *Service) {
+		s.privateHosts = allow
+	}
+}
+
+// NewService creates a Service over the webhooks and deliveries of store.
+// Call Run to start delivering.
+func NewService(store Store, opts ...Option) *Service {
+	s := &Service{
+		store:        store,
+		maxAttempts:  DefaultMaxAttempts,
+		backoff:      DefaultBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+		retention:    DefaultRetention,
+		onError:      func(error) {},
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.client = newClient(s.privateHosts)
+	return s
+}
+
+// newClient returns the client deliveries are sent with. It never follows
+// redirects, and unless privateHosts is set it refuses to connect to a
+// blocked address, whatever the URL's host name resolves to when sent.
+func newClient(privateHosts bool) *resty.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !privateHosts {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("conexión a %s bloqueada: dirección privada", host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// sin proxy: el control de arriba tiene que ver la dirección real
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return resty.New().
+		SetTransport(transport).
+		SetRedirectPolicy(resty.NoRedirectPolicy())
+}
+
+// blockedPrefixes are the non-public ranges that net.IP has no method for.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, embeds any IPv4 address
+}
+
+// blockedIP reports whether ip is loopback, link-local, private or
+// otherwise not a public unicast address.
+func blockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedHost reports whether host is a name or IP literal that refers to
+// a blocked address. Other names are checked when a delivery connects.
+func blockedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && blockedIP(ip)
+}
+
+// Register adds a webhook for rawURL, which must be an absolute http or
+// https URL to a public host, receiving the given event types (all of
+// them if empty). The returned webhook carries its signing secret.
+// Returns an error wrapping ErrInvalidWebhook otherwise.
+func (s *Service) Register(rawURL string, types []sale.EventType) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: la url debe ser http o https absoluta", ErrInvalidWebhook)
+	}
+	if !s.privateHosts && blockedHost(u.Hostname()) {
+		return nil, fmt.Errorf("%w: la url no puede apuntar a una red privada", ErrInvalidWebhook)
+	}
+	for _, t := range types {
+		if !slices.Contains(events, t) {
+			return nil, fmt.Errorf("%w: evento desconocido %q", ErrInvalidWebhook, t)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	w := &Webhook{
+		ID:        uuid.NewString(),
+		URL:       u.String(),
+		Events:    append([]sale.EventType{}, types...),
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: s.now(),
+	}
+
+	if err := s.store.SaveWebhook(*w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Get returns the webhook with the given ID, without its secret.
+// Returns ErrNotFound if it does not exist.
+func (s *Service) Get(id string) (*Webhook, error) {
+	w, err := s.store.Webhook(id)
+	if err != nil {
+		return nil, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+// Deliveries returns the deliveries made to a webhook, oldest first. Only
+// the newest succeeded ones are kept (see WithRetention).
+// Returns ErrNotFound if the webhook does not exist.
+func (s *Service) Deliveries(id string) ([]Delivery, error) {
+	if _, err := s.store.Webhook(id); err != nil {
+		return nil, err
+	}
+	return s.store.Deliveries(id)
+}
+
+// DeadLetters returns every delivery that exhausted its attempts, across
+// all webhooks, oldest first.
+func (s *Service) DeadLetters() ([]Delivery, error) {
+	return s.store.DeadLetters()
+}
+
+// Handle stores a pending delivery of e for every webhook subscribed to
+// its type. It implements sale.Subscriber: it only fails if the store
+// does, so the dispatcher offers the event again; attempts and retries are
+// handled by Run. Handling an event twice does not duplicate deliveries.
+func (s *Service) Handle(ctx context.Context, e sale.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	hooks, err := s.store.Webhooks()
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	var deliveries []Delivery
+	for _, w := range hooks {
+		if !w.wants(e.Type) {
+			continue
+		}
+		deliveries = append(deliveries, Delivery{
+			ID:            uuid.NewString(),
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Status:        StatusPending,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+			payload:       payload,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := s.store.AddDeliveries(deliveries...); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"sales-api/internal/sale"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a webhook endpoint that answers with the given statuses in
+// turn, repeating the last one, and records what it got.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+// newTestService returns a Service with a clock the test moves by hand,
+// allowed to deliver to the loopback receivers of httptest.
+func newTestService(opts ...Option) (*Service, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewService(NewMemoryStore(), append([]Option{WithPrivateHosts(true)}, opts...)...)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// deliver runs s.Deliver, failing the test on a store error.
+func deliver(t *testing.T, s *Service) int {
+	t.Helper()
+	n, err := s.Deliver(context.Background())
+	require.NoError(t, err)
+	return n
+}
+
+func testEvent(t sale.EventType) sale.Event {
+	return sale.Event{
+		ID:     "evt-" + string(t),
+		Seq:    1,
+		Type:   t,
+		SaleID: "s1",
+		At:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Sale:   sale.Sale{ID: "s1", UserID: "u1", Amount: sale.NewMoney(100, sale.DefaultCurrency)},
+	}
+}
+
+func TestService_SignedDelivery(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s, _ := newTestService()
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, w.Secret)
+
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+	assert.Equal(t, 1, deliver(t, s))
+
+	require.Len(t, rcv.requests, 1)
+	req, body := rcv.requests[0], rcv.bodies[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, string(sale.SaleCreated), req.Header.Get(EventHeader))
+	assert.True(t, Verify(w.Secret, req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)))
+	assert.False(t, Verify("otro-secreto", req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)))
+
+	var got sale.Event
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, "evt-SaleCreated", got.ID)
+	assert.Equal(t, "s1", got.Sale.ID)
+
+	deliveries, err := s.Deliveries(w.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+	assert.Equal(t, req.Header.Get(DeliveryHeader), deliveries[0].ID)
+
+	// el secreto solo se muestra al registrar
+	found, err := s.Get(w.ID)
+	require.NoError(t, err)
+	assert.Empty(t, found.Secret)
+}
+
+func TestService_RetriesWithBackoff(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s, now := newTestService(WithBackoff(time.Second, time.Minute))
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleApproved)))
+
+	assert.Equal(t, 1, deliver(t, s))
+	deliveries, _ := s.Deliveries(w.ID)
+	assert.Equal(t, StatusPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseStatus)
+	assert.Equal(t, now.Add(time.Second), deliveries[0].NextAttemptAt)
+
+	// antes del backoff no se reintenta
+	assert.Equal(t, 0, deliver(t, s))
+
+	*now = now.Add(time.Second)
+	assert.Equal(t, 1, deliver(t, s))
+	deliveries, _ = s.Deliveries(w.ID)
+	assert.Equal(t, now.Add(2*time.Second), deliveries[0].NextAttemptAt)
+
+	*now = now.Add(2 * time.Second)
+	assert.Equal(t, 1, deliver(t, s))
+	deliveries, _ = s.Deliveries(w.ID)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Empty(t, deliveries[0].LastError)
+	dead, err := s.DeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+func TestService_DeadLetters(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusGone}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s, now := newTestService(WithMaxAttempts(3), WithBackoff(time.Second, 2*time.Second))
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleRejected)))
+
+	for range 3 {
+		assert.Equal(t, 1, deliver(t, s))
+		*now = now.Add(time.Minute)
+	}
+	assert.Equal(t, 0, deliver(t, s))
+	assert.Len(t, rcv.requests, 3)
+
+	dead, err := s.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, w.ID, dead[0].WebhookID)
+	assert.Equal(t, StatusDead, dead[0].Status)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, http.StatusGone, dead[0].ResponseStatus)
+	assert.Contains(t, dead[0].LastError, "410")
+}
+
+func TestService_DoesNotFollowRedirects(t *testing.T) {
+	target := &receiver{statuses: []int{http.StatusOK}}
+	internal := httptest.NewServer(target)
+	defer internal.Close()
+	srv := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusTemporaryRedirect))
+	defer srv.Close()
+
+	s, _ := newTestService()
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+
+	assert.Equal(t, 1, deliver(t, s))
+	deliveries, _ := s.Deliveries(w.ID)
+	assert.Equal(t, StatusPending, deliveries[0].Status)
+	assert.Empty(t, target.requests)
+}
+
+func TestClient_BlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	// un nombre público puede resolver a una dirección interna: se revisa al conectar
+	_, err := newClient(false).R().Get(srv.URL)
+	assert.ErrorContains(t, err, "bloqueada")
+
+	_, err = newClient(true).R().Get(srv.URL)
+	assert.NoError(t, err)
+}
+
+func TestBlockedIP(t *testing.T) {
+	for _, ip := range []string{
+		"0.1.2.3", "100.64.0.1", "100.127.255.254", "::ffff:100.64.0.1",
+		"64:ff9b::7f00:1", "64:ff9b::5db8:d822",
+	} {
+		assert.True(t, blockedIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"100.63.255.255", "100.128.0.0", "93.184.216.34", "2606:2800:220:1::"} {
+		assert.False(t, blockedIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestService_Register(t *testing.T) {
+	s, _ := newTestService()
+
+	t.Run("url inválida", func(t *testing.T) {
+		for _, u := range []string{"", "ftp://example.com", "/hooks", "http://"} {
+			_, err := s.Register(u, nil)
+			assert.ErrorIs(t, err, ErrInvalidWebhook, u)
+		}
+	})
+
+	t.Run("red privada", func(t *testing.T) {
+		s := NewService(NewMemoryStore())
+		for _, u := range []string{
+			"http://localhost:8080", "http://api.localhost", "http://127.0.0.1", "http://[::1]:9000",
+			"http://10.0.0.5", "http://192.168.1.1", "http://172.16.0.1", "http://169.254.169.254/latest",
+			"http://[fe80::1]", "http://0.0.0.0", "http://[::ffff:127.0.0.1]",
+			"http://0.1.2.3", "http://100.64.0.1", "http://[64:ff9b::a00:5]",
+		} {
+			_, err := s.Register(u, nil)
+			assert.ErrorIs(t, err, ErrInvalidWebhook, u)
+		}
+		_, err := s.Register("https://93.184.216.34/hooks", nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("evento desconocido", func(t *testing.T) {
+		_, err := s.Register("http://example.com", []sale.EventType{"SaleDeleted"})
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+
+	t.Run("webhook inexistente", func(t *testing.T) {
+		_, err := s.Deliveries("nope")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("filtra por tipo de evento", func(t *testing.T) {
+		approvals, err := s.Register("http://example.com/approvals", []sale.EventType{sale.SaleApproved})
+		require.NoError(t, err)
+		all, err := s.Register("http://example.com/all", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+		require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleApproved)))
+
+		deliveries, _ := s.Deliveries(approvals.ID)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, sale.SaleApproved, deliveries[0].EventType)
+
+		deliveries, _ = s.Deliveries(all.ID)
+		assert.Len(t, deliveries, 2)
+	})
+}
+
+func TestService_HandleTwice(t *testing.T) {
+	s, _ := newTestService()
+	w, err := s.Register("https://example.com", nil)
+	require.NoError(t, err)
+
+	// el dispatcher puede entregar un evento más de una vez
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+
+	deliveries, err := s.Deliveries(w.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestService_Retention(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	s, _ := newTestService(WithRetention(2))
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+
+	for i, typ := range []sale.EventType{sale.SaleCreated, sale.SaleApproved, sale.SaleRejected} {
+		e := testEvent(typ)
+		e.Seq = int64(i + 1)
+		require.NoError(t, s.Handle(context.Background(), e))
+		assert.Equal(t, 1, deliver(t, s))
+	}
+
+	deliveries, err := s.Deliveries(w.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, sale.SaleApproved, deliveries[0].EventType)
+	assert.Equal(t, sale.SaleRejected, deliveries[1].EventType)
+}
+
+func TestService_InterruptedAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// el proceso se apaga mientras espera la respuesta
+		io.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	s, _ := newTestService()
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+
+	n, err := s.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// no cuenta como intento: sigue pendiente para el próximo arranque
+	deliveries, _ := s.Deliveries(w.ID)
+	assert.Equal(t, StatusPending, deliveries[0].Status)
+	assert.Zero(t, deliveries[0].Attempts)
+}
+
+func TestService_ResumesAfterRestart(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "sales.db")
+
+	// primer proceso: registra y acepta el evento, pero se cae antes de enviarlo
+	storage, err := sale.NewSQLiteStorage(path)
+	require.NoError(t, err)
+	store, err := NewSQLiteStore(storage.DB())
+	require.NoError(t, err)
+	s := NewService(store, WithPrivateHosts(true))
+	w, err := s.Register(srv.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Handle(context.Background(), testEvent(sale.SaleCreated)))
+	require.NoError(t, storage.Close())
+
+	s = NewService(newTestSQLiteStore(t, path), WithPrivateHosts(true))
+	assert.Equal(t, 1, deliver(t, s))
+	require.Len(t, rcv.requests, 1)
+	// el secreto también sobrevive: la firma sigue siendo válida
+	req, body := rcv.requests[0], rcv.bodies[0]
+	assert.True(t, Verify(w.Secret, req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)))
+
+	deliveries, err := s.Deliveries(w.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+}
@@ -1,17 +1,177 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"platform/health"
+	"platform/lifecycle"
+	"platform/logging"
 	"sales-api/api"
+	"sales-api/internal/config"
+	"sales-api/internal/sale"
+	"sales-api/internal/stream"
+	"sales-api/internal/usersclient"
+	"sales-api/internal/webhook"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// flagKeys maps the command-line flags to the configuration keys they
+// override.
+var flagKeys = map[string]string{
+	"addr":               "addr",
+	"storage":            "storage.kind",
+	"db":                 "storage.db",
+	"rates":              "rates",
+	"idempotency-window": "idempotency_window",
+	"admin-token":        "admin_token",
+}
+
 func main() {
-	r := gin.Default()
-	api.InitRoutes(r)
+	configPath := flag.String("config", os.Getenv("SALES_CONFIG"), "YAML or TOML configuration file (default $SALES_CONFIG)")
+	flag.String("addr", "", "address to listen on (default :8081)")
+	flag.String("storage", "", "sale storage backend: memory or sqlite (default memory)")
+	flag.String("db", "", "path to the SQLite database file, for storage=sqlite (default sales.db)")
+	flag.String("rates", "", "JSON file with exchange rates; enables convert_to on GET /sales")
+	flag.String("idempotency-window", "", "how long POST /sales responses are replayed for a repeated Idempotency-Key (default 24h)")
+	flag.String("admin-token", "", "token required in X-Admin-Token by admin endpoints; empty disables them")
+	flag.Parse()
+
+	// los flags indicados explícitamente pisan al archivo y al entorno
+	overrides := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			overrides[key] = f.Value.String()
+		}
+	})
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		panic(fmt.Errorf("error loading configuration: %v", err))
+	}
+
+	logger, err := logging.New(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		panic(fmt.Errorf("error initializing logger: %v", err))
+	}
+
+	storage, err := newStorage(cfg.Storage)
+	if err != nil {
+		panic(fmt.Errorf("error initializing storage: %v", err))
+	}
+
+	// cache de usuarios validados delante del cliente HTTP
+	usersHTTP := usersclient.New(cfg.Users.URL,
+		usersclient.WithTimeout(cfg.Users.Timeout),
+		usersclient.WithRetries(cfg.Users.Retries, usersclient.DefaultBackoff),
+	)
+	users := usersclient.NewCachedClient(usersHTTP,
+		usersclient.DefaultCacheSize, usersclient.DefaultCacheTTL, usersclient.DefaultNegativeTTL,
+	)
+
+	gin.SetMode(cfg.Mode)
+	// sin middlewares de Gin: InitRoutes registra el access log con zap,
+	// las métricas y el recovery
+	r := gin.New()
+	server := &http.Server{Addr: cfg.Addr, Handler: r}
+	app := lifecycle.New(server,
+		lifecycle.WithShutdownTimeout(cfg.Shutdown.Timeout),
+		lifecycle.WithReadinessDelay(cfg.Shutdown.Delay),
+	)
+
+	// entrega los eventos de dominio del outbox a los suscriptores
+	dispatcher := sale.NewDispatcher(storage, sale.WithDispatchErrorHandler(func(e sale.Event, subscriber string, err error) {
+		logger.Warn("event not delivered", zap.String("event_id", e.ID), zap.String("type", string(e.Type)),
+			zap.String("subscriber", subscriber), zap.Error(err))
+	}))
+
+	// entrega los eventos a los webhooks registrados, con reintentos; con
+	// SQLite los webhooks y sus entregas pendientes sobreviven reinicios
+	var hooks webhook.Store = webhook.NewMemoryStore()
+	if db, ok := storage.(*sale.SQLiteStorage); ok {
+		if hooks, err = webhook.NewSQLiteStore(db.DB()); err != nil {
+			panic(fmt.Errorf("error initializing webhook storage: %v", err))
+		}
+	}
+	webhooks := webhook.NewService(hooks, webhook.WithErrorHandler(func(err error) {
+		logger.Warn("webhook deliveries not processed", zap.Error(err))
+	}))
+	dispatcher.Subscribe("webhooks", webhooks.Handle)
+	// reenvía los eventos a los clientes de GET /sales/stream
+	broker := stream.NewBroker()
+	dispatcher.Subscribe("stream", broker.Publish)
+	// los streams no terminan solos: se cierran al empezar a drenar
+	server.RegisterOnShutdown(broker.Close)
+
+	app.Go("dispatcher", dispatcher.Run)
+	app.Go("webhooks", webhooks.Run)
+	if closer, ok := storage.(io.Closer); ok {
+		app.OnShutdown("storage", func(context.Context) error {
+			return closer.Close()
+		})
+	}
+	app.OnShutdown("logger", func(context.Context) error {
+		// Sync falla en algunas terminales (stderr no sincronizable); no es un error real
+		_ = logger.Sync()
+		return nil
+	})
+
+	// lo que GET /readyz revisa antes de declararse listo
+	// (/readyz solo dice qué check falló; el error queda en el log)
+	checker := health.NewChecker(
+		health.WithTimeout(cfg.Health.Timeout),
+		health.WithErrorHandler(func(name string, err error) {
+			logger.Warn("health check failed", zap.String("check", name), zap.Error(err))
+		}),
+	)
+	checker.Register("storage", storage.Ping)
+	checker.Register("users_service", usersHTTP.Ping)
+	checker.Register("users_breaker", func(context.Context) error {
+		if state := usersHTTP.BreakerState(); state == usersclient.StateOpen {
+			return fmt.Errorf("circuit breaker %s", state)
+		}
+		return nil
+	})
+
+	opts := []api.Option{
+		api.WithDispatcher(dispatcher),
+		api.WithWebhooks(webhooks),
+		api.WithBroker(broker),
+		api.WithHealth(checker),
+		api.WithReadiness(app.Ready),
+		api.WithUsersCache(users),
+	}
+	if cfg.RatesPath != "" {
+		rates, err := sale.LoadStaticRates(cfg.RatesPath)
+		if err != nil {
+			panic(fmt.Errorf("error loading exchange rates: %v", err))
+		}
+		opts = append(opts, api.WithServiceOptions(sale.WithRateProvider(rates)))
+	}
+	api.InitRoutes(r, cfg, api.Dependencies{Storage: storage, Users: users, Logger: logger}, opts...)
+
+	// SIGINT o SIGTERM inician el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Run(ctx); err != nil {
+		panic(fmt.Errorf("error running server: %v", err))
+	}
+}
 
-	if err := r.Run(":8081"); err != nil {
-		panic(fmt.Errorf("error trying to start server: %v", err))
+// newStorage builds the configured sale storage.
+func newStorage(cfg config.StorageConfig) (sale.Storage, error) {
+	switch cfg.Kind {
+	case "memory":
+		return sale.NewLocalStorage(), nil
+	case "sqlite":
+		return sale.NewSQLiteStorage(cfg.DB)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Kind)
 	}
 }
@@ -0,0 +1,40 @@
+// Package etag turns resource versions into entity tags and back, for
+// conditional updates with If-Match.
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidIfMatch is returned when the If-Match header is not a single
+// entity tag produced by Format.
+var ErrInvalidIfMatch = errors.New("invalid If-Match header")
+
+// Format formats a resource version as a strong entity tag, e.g. "3".
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch extracts the expected version from an If-Match header value.
+// It returns nil for "*" (any version) and accepts weak tags (W/"3") since
+// the version number is all that is compared.
+func ParseIfMatch(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return nil, ErrInvalidIfMatch
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil {
+		return nil, ErrInvalidIfMatch
+	}
+
+	return &version, nil
+}
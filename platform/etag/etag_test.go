@@ -0,0 +1,36 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, `"3"`, Format(3))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	t.Run("any version", func(t *testing.T) {
+		version, err := ParseIfMatch(" * ")
+		require.NoError(t, err)
+		assert.Nil(t, version)
+	})
+
+	t.Run("strong and weak tags", func(t *testing.T) {
+		for _, header := range []string{Format(3), `W/"3"`} {
+			version, err := ParseIfMatch(header)
+			require.NoError(t, err)
+			require.NotNil(t, version)
+			assert.Equal(t, 3, *version)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, header := range []string{"", "3", `"`, `"abc"`, `"3", "4"`} {
+			_, err := ParseIfMatch(header)
+			assert.ErrorIs(t, err, ErrInvalidIfMatch, header)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Liveness handles GET /healthz: the process is up and serving. It does
+// not look at dependencies, so a failing one never gets the service
+// restarted.
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+// Readiness handles GET /readyz, running every check of checker and
+// answering 503 with the report if any of them is down.
+func Readiness(checker *Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Check(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var down bool
+	checker := NewChecker()
+	checker.Register("storage", func(context.Context) error {
+		if down {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	r := gin.New()
+	r.GET("/healthz", Liveness)
+	r.GET("/readyz", Readiness(checker))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get("/readyz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"up"`)
+
+	down = true
+	rec = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error":"check failed"`)
+	assert.NotContains(t, rec.Body.String(), "disk full")
+
+	// liveness does not depend on the checks
+	rec = get("/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+}
@@ -0,0 +1,186 @@
+// Package health aggregates the checks that decide whether a service is
+// ready to receive traffic, and serves them on GET /healthz and
+// GET /readyz.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check.
+const DefaultTimeout = 2 * time.Second
+
+// Check statuses.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Messages reported in Result.Error. The report is served without
+// authentication, so the error of a check, which may name hosts, paths or
+// queries, only reaches the handler set with WithErrorHandler.
+const (
+	MessageTimeout = "timeout"
+	MessageFailed  = "check failed"
+)
+
+// CheckFunc reports whether a dependency works, returning an error
+// describing the problem if it does not. It should honour ctx.
+type CheckFunc func(ctx context.Context) error
+
+// ErrNotReady is returned by the checks built with ReadyCheck.
+var ErrNotReady = errors.New("not ready")
+
+// ReadyCheck adapts ready, e.g. lifecycle.App.Ready, to a check that is
+// down while it reports false, so the service stops getting traffic as
+// soon as it starts shutting down.
+func ReadyCheck(ready func() bool) CheckFunc {
+	return func(context.Context) error {
+		if !ready() {
+			return ErrNotReady
+		}
+		return nil
+	}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status string `json:"status"`
+	// LatencyMS is how long the check took, in milliseconds.
+	LatencyMS float64 `json:"latency_ms"`
+	// Error is MessageTimeout or MessageFailed if the check is down.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. Status is StatusUp only if every
+// check is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the registered checks.
+type Checker struct {
+	timeout time.Duration
+	onError func(name string, err error)
+
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]CheckFunc
+}
+
+// Option configures optional Checker behaviour.
+type Option func(*Checker)
+
+// WithTimeout bounds each check. A check that does not return in time is
+// reported as down.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = d
+	}
+}
+
+// WithErrorHandler sets a function called with the error of every check
+// that is down, e.g. to log it. It may be called concurrently.
+func WithErrorHandler(fn func(name string, err error)) Option {
+	return func(c *Checker) {
+		c.onError = fn
+	}
+}
+
+// NewChecker creates a Checker without checks.
+func NewChecker(opts ...Option) *Checker {
+	c := &Checker{
+		timeout: DefaultTimeout,
+		onError: func(string, error) {},
+		checks:  map[string]CheckFunc{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register adds a check under name, replacing any check with that name.
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = fn
+}
+
+// Check runs every check concurrently and reports their outcome.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	names := append([]string(nil), c.names...)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, fn := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, names[i], fn)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run runs one check within the timeout, even if fn ignores ctx.
+func (c *Checker) run(ctx context.Context, name string, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = MessageFailed
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = MessageTimeout
+		}
+		c.onError(name, err)
+	}
+	return result
+}
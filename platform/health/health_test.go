@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Check(t *testing.T) {
+	t.Run("ready without checks", func(t *testing.T) {
+		report := NewChecker().Check(context.Background())
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("a failing check takes the report down", func(t *testing.T) {
+		var mu sync.Mutex
+		failed := map[string]error{}
+		c := NewChecker(WithErrorHandler(func(name string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed[name] = err
+		}))
+		c.Register("storage", func(context.Context) error { return nil })
+		c.Register("users", func(context.Context) error { return errors.New("dial tcp 10.0.0.7:8080: connection refused") })
+
+		report := c.Check(context.Background())
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["storage"].Status)
+		assert.Empty(t, report.Checks["storage"].Error)
+		assert.Equal(t, StatusDown, report.Checks["users"].Status)
+		// the report does not reveal the error, the handler gets it
+		assert.Equal(t, MessageFailed, report.Checks["users"].Error)
+		assert.Len(t, failed, 1)
+		assert.EqualError(t, failed["users"], "dial tcp 10.0.0.7:8080: connection refused")
+	})
+
+	t.Run("timeout even if the check ignores its context", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+
+		c := NewChecker(WithTimeout(20 * time.Millisecond))
+		c.Register("slow", func(context.Context) error { <-block; return nil })
+
+		start := time.Now()
+		report := c.Check(context.Background())
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, StatusDown, report.Checks["slow"].Status)
+		assert.Equal(t, MessageTimeout, report.Checks["slow"].Error)
+		assert.GreaterOrEqual(t, report.Checks["slow"].LatencyMS, 20.0)
+	})
+
+	t.Run("checks run concurrently", func(t *testing.T) {
+		c := NewChecker()
+		for _, name := range []string{"a", "b", "c"} {
+			c.Register(name, func(context.Context) error {
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			})
+		}
+
+		start := time.Now()
+		report := c.Check(context.Background())
+		assert.Less(t, time.Since(start), 140*time.Millisecond)
+		assert.Len(t, report.Checks, 3)
+	})
+
+	t.Run("a panic is reported as down", func(t *testing.T) {
+		var got error
+		c := NewChecker(WithErrorHandler(func(_ string, err error) { got = err }))
+		c.Register("broken", func(context.Context) error { panic("boom") })
+		c.Register("broken", func(context.Context) error { panic("boom again") })
+
+		report := c.Check(context.Background())
+		assert.Len(t, report.Checks, 1)
+		assert.Equal(t, MessageFailed, report.Checks["broken"].Error)
+		assert.EqualError(t, got, "panic: boom again")
+	})
+}
+
+func TestReadyCheck(t *testing.T) {
+	ready := false
+	check := ReadyCheck(func() bool { return ready })
+	assert.ErrorIs(t, check(context.Background()), ErrNotReady)
+
+	ready = true
+	assert.NoError(t, check(context.Background()))
+}
@@ -0,0 +1,190 @@
+// Package lifecycle runs the HTTP server and the background workers of a
+// service, and shuts them down in order when asked to stop.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds the whole shutdown: draining the server,
+// stopping the workers and running the hooks.
+const DefaultShutdownTimeout = 15 * time.Second
+
+// App ties the lifetime of an http.Server, its workers and its shutdown
+// hooks together.
+//
+// On shutdown it first reports itself as not ready, so load balancers
+// stop sending traffic, optionally waits for that to propagate, then
+// drains in-flight requests, stops the workers and finally runs the hooks
+// in the order they were added, e.g. flush storage, then sync the logger.
+type App struct {
+	server  *http.Server
+	timeout time.Duration
+	delay   time.Duration
+
+	ready atomic.Bool
+
+	mu    sync.Mutex
+	hooks []hook
+
+	workers    sync.WaitGroup
+	workerCtx  context.Context
+	stopWorker context.CancelFunc
+	workerErrs chan error
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Option configures optional App behaviour.
+type Option func(*App)
+
+// WithShutdownTimeout bounds the whole shutdown. Requests still running
+// when it expires are cut off.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.timeout = d
+	}
+}
+
+// WithReadinessDelay sets how long the App keeps serving after reporting
+// itself as not ready, so load balancers notice before it stops accepting
+// connections.
+func WithReadinessDelay(d time.Duration) Option {
+	return func(a *App) {
+		a.delay = d
+	}
+}
+
+// New creates an App for server.
+func New(server *http.Server, opts ...Option) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &App{
+		server:     server,
+		timeout:    DefaultShutdownTimeout,
+		workerCtx:  ctx,
+		stopWorker: cancel,
+		workerErrs: make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Ready reports whether the App is serving and not shutting down.
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Go runs fn in the background until shutdown, when its context is
+// cancelled and the App waits for it to return. fn should return
+// ctx.Err() once cancelled; any other error is reported by Run.
+func (a *App) Go(name string, fn func(ctx context.Context) error) {
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		if err := fn(a.workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+			select {
+			case a.workerErrs <- fmt.Errorf("worker %s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// OnShutdown adds a hook run during shutdown, after the server is drained
+// and the workers stopped. Hooks run in the order they were added, even
+// if earlier ones fail; ctx expires with the shutdown timeout.
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hooks = append(a.hooks, hook{name: name, fn: fn})
+}
+
+// Run listens on the server's address and serves until ctx is cancelled,
+// typically by a signal, then shuts down. It returns the errors of
+// serving and of every shutdown step.
+func (a *App) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return errors.Join(err, a.shutdown())
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve is like Run, but serves on an existing listener.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.server.Serve(ln)
+	}()
+	a.ready.Store(true)
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		// the server failed on its own: shut everything else down anyway
+		errs = append(errs, err)
+	case err := <-a.workerErrs:
+		errs = append(errs, err)
+	}
+
+	return errors.Join(append(errs, a.shutdown())...)
+}
+
+// shutdown runs every shutdown step in order, within the timeout.
+func (a *App) shutdown() error {
+	a.ready.Store(false)
+	if a.delay > 0 {
+		time.Sleep(a.delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	var errs []error
+	if err := a.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("draining server: %w", err))
+		// cut off the connections that did not finish in time
+		a.server.Close()
+	}
+
+	a.stopWorker()
+	stopped := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("stopping workers: %w", ctx.Err()))
+	}
+	select {
+	case err := <-a.workerErrs:
+		errs = append(errs, err)
+	default:
+	}
+
+	a.mu.Lock()
+	hooks := append([]hook(nil), a.hooks...)
+	a.mu.Unlock()
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
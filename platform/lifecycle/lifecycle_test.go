@@ -0,0 +1,151 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// start serves handler with a new App until the returned cancel is called.
+// Run's result is sent on the returned channel.
+func start(t *testing.T, handler http.Handler, opts ...Option) (*App, string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	app := New(&http.Server{Handler: handler}, opts...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Serve(ctx, ln) }()
+	require.Eventually(t, app.Ready, time.Second, time.Millisecond)
+	return app, "http://" + ln.Addr().String(), cancel, done
+}
+
+func TestApp_GracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "ok")
+	})
+	app, url, cancel, done := start(t, handler)
+
+	var mu sync.Mutex
+	var steps []string
+	step := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, s)
+	}
+	app.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		step("worker")
+		return ctx.Err()
+	})
+	app.OnShutdown("storage", func(context.Context) error { step("storage"); return nil })
+	app.OnShutdown("logger", func(context.Context) error { step("logger"); return nil })
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+	require.Eventually(t, func() bool { return !app.Ready() }, time.Second, time.Millisecond)
+
+	// the request in flight finishes before anything else is shut down
+	select {
+	case <-done:
+		t.Fatal("shutdown finished with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Lock()
+	assert.Empty(t, steps)
+	mu.Unlock()
+
+	close(release)
+	r := <-response
+	require.NoError(t, r.err)
+	assert.Equal(t, "ok", r.body)
+
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"worker", "storage", "logger"}, steps)
+
+	// no longer accepts connections
+	_, err := http.Get(url)
+	assert.Error(t, err)
+}
+
+func TestApp_ShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	app, url, cancel, done := start(t, handler, WithShutdownTimeout(50*time.Millisecond))
+
+	hookRan := false
+	app.OnShutdown("flush", func(ctx context.Context) error {
+		hookRan = true
+		return errors.New("flush failed")
+	})
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	err := <-done
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "shutdown hook flush: flush failed")
+	// the hooks run even if draining did not finish in time
+	assert.True(t, hookRan)
+}
+
+func TestApp_WorkerFailureShutsDown(t *testing.T) {
+	app, _, cancel, done := start(t, http.NotFoundHandler())
+	defer cancel()
+
+	app.Go("dispatcher", func(context.Context) error {
+		return errors.New("storage gone")
+	})
+
+	err := <-done
+	assert.ErrorContains(t, err, "worker dispatcher: storage gone")
+	assert.False(t, app.Ready())
+}
+
+func TestApp_RunListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	app := New(&http.Server{Addr: ln.Addr().String()})
+	closed := false
+	app.OnShutdown("storage", func(context.Context) error { closed = true; return nil })
+
+	assert.Error(t, app.Run(context.Background()))
+	assert.True(t, closed)
+}
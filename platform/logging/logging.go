@@ -0,0 +1,83 @@
+// Package logging builds the zap logger of a service and logs every HTTP
+// request it serves, tagged with its request ID.
+package logging
+
+import (
+	"context"
+	"time"
+
+	"platform/requestid"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New builds a production zap logger at level (debug, info, warn or
+// error) that writes in format (json or console).
+func New(level, format string) (*zap.Logger, error) {
+	lvl, err := zap.ParseAtomicLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	zc := zap.NewProductionConfig()
+	zc.Level = lvl
+	zc.Encoding = format
+	return zc.Build()
+}
+
+type loggerKey struct{}
+
+// Middleware identifies every request and logs it once served. It keeps
+// a valid X-Request-ID sent by the client or assigns a new one, echoes it
+// in the response and puts it, together with a logger tagged with it, in
+// the request context, where FromContext and requestid.FromContext find
+// them.
+//
+// Requests are logged after the handlers below it return, so a recovery
+// middleware should be registered after it for panics to be logged as
+// 500s.
+func Middleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+
+		reqLogger := logger.With(zap.String("request_id", id))
+		ctx := requestid.NewContext(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(context.WithValue(ctx, loggerKey{}, reqLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := zapcore.InfoLevel
+		if status >= 500 {
+			level = zapcore.ErrorLevel
+		}
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("route", c.FullPath()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+		reqLogger.Log(level, "request served", fields...)
+	}
+}
+
+// FromContext returns the logger Middleware put in ctx, or fallback if
+// the request did not go through it.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return fallback
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"platform/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNew(t *testing.T) {
+	logger, err := New("warn", "console")
+	require.NoError(t, err)
+	assert.False(t, logger.Core().Enabled(zap.InfoLevel))
+	assert.True(t, logger.Core().Enabled(zap.WarnLevel))
+
+	_, err = New("loud", "json")
+	assert.Error(t, err)
+	_, err = New("info", "xml")
+	assert.Error(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	fallback := zap.NewNop()
+
+	r := gin.New()
+	r.Use(Middleware(zap.New(core)), gin.Recovery())
+	r.GET("/items/:id", func(c *gin.Context) {
+		FromContext(c.Request.Context(), fallback).Info("handling", zap.String("id", c.Param("id")))
+		c.String(http.StatusOK, requestid.FromContext(c.Request.Context()))
+	})
+	r.GET("/panic", func(*gin.Context) { panic("boom") })
+
+	get := func(url, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	served := func(t *testing.T, id string) observer.LoggedEntry {
+		entries := logs.FilterMessage("request served").FilterField(zap.String("request_id", id)).TakeAll()
+		require.Len(t, entries, 1)
+		return entries[0]
+	}
+
+	t.Run("keeps the client ID", func(t *testing.T) {
+		rec := get("/items/7", "req-123")
+		assert.Equal(t, "req-123", rec.Header().Get(requestid.Header))
+		assert.Equal(t, "req-123", rec.Body.String())
+
+		handled := logs.FilterMessage("handling").TakeAll()
+		require.Len(t, handled, 1)
+		assert.Equal(t, "req-123", handled[0].ContextMap()["request_id"])
+
+		fields := served(t, "req-123").ContextMap()
+		assert.Equal(t, "/items/:id", fields["route"])
+		assert.Equal(t, "/items/7", fields["path"])
+		assert.EqualValues(t, http.StatusOK, fields["status"])
+	})
+
+	t.Run("replaces a missing or invalid ID", func(t *testing.T) {
+		for _, id := range []string{"", "with space"} {
+			rec := get("/items/7", id)
+			got := rec.Header().Get(requestid.Header)
+			assert.NotEqual(t, id, got)
+			assert.True(t, requestid.Valid(got))
+			assert.Equal(t, got, rec.Body.String())
+			served(t, got)
+		}
+		logs.TakeAll()
+	})
+
+	t.Run("logs a recovered panic as an error", func(t *testing.T) {
+		rec := get("/panic", "req-456")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		entry := served(t, "req-456")
+		assert.Equal(t, zap.ErrorLevel, entry.Level)
+		assert.EqualValues(t, http.StatusInternalServerError, entry.ContextMap()["status"])
+	})
+
+	t.Run("fallback outside a request", func(t *testing.T) {
+		assert.Same(t, fallback, FromContext(context.Background(), fallback))
+	})
+}
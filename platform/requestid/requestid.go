@@ -0,0 +1,50 @@
+// Package requestid carries the ID that correlates a request across
+// services, sent and received in the X-Request-ID header.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header that carries the request ID.
+const Header = "X-Request-ID"
+
+// MaxLength bounds the IDs accepted from clients, so a caller cannot flood
+// the logs.
+const MaxLength = 128
+
+type contextKey struct{}
+
+// New returns a new random request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id, received from a client, can be used as is: it
+// must be non-empty, at most MaxLength long and made of visible ASCII
+// characters.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx that carries id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "" if there is
+// none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
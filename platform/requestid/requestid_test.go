@@ -0,0 +1,27 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "abc-123", FromContext(NewContext(ctx, "abc-123")))
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(New()))
+	assert.True(t, Valid("abc-123_x.y"))
+	assert.True(t, Valid(strings.Repeat("a", MaxLength)))
+
+	assert.False(t, Valid(""))
+	assert.False(t, Valid(strings.Repeat("a", MaxLength+1)))
+	assert.False(t, Valid("with space"))
+	assert.False(t, Valid("new\nline"))
+	assert.False(t, Valid("ñ"))
+}
@@ -0,0 +1,180 @@
+// Package settings loads the settings of a service from an optional YAML
+// or TOML file, from environment variables and from explicit overrides,
+// e.g. flags. Each service declares its settings as a list of keys with
+// the function that parses a value into its configuration.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalid is returned when a setting is unknown, malformed or out of
+// range.
+var ErrInvalid = errors.New("invalid configuration")
+
+// Setting is a configurable value, identified by its key. Keys are dotted:
+// storage.kind is the kind key of the storage section of the file.
+type Setting struct {
+	Key string
+	Set func(v string) error
+}
+
+// String sets *p to the value as is.
+func String(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+// Duration parses the value with time.ParseDuration into *p.
+func Duration(p *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*p = d
+		return nil
+	}
+}
+
+// Int parses the value as a decimal integer into *p.
+func Int(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = n
+		return nil
+	}
+}
+
+// Load sets each of settings from, in increasing order of precedence: the
+// file at path (if path is not empty), its environment variable (see
+// EnvVar) and overrides, which maps keys to values. Settings nobody sets
+// keep their current value.
+//
+// The file is YAML (.yaml, .yml) or TOML (.toml). Errors wrap ErrInvalid,
+// except those reading the file.
+func Load(settings []Setting, envPrefix, path string, overrides map[string]string) error {
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		if err := apply(settings, values, "file "+path); err != nil {
+			return err
+		}
+	}
+
+	env := map[string]string{}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(EnvVar(envPrefix, s.Key)); ok {
+			env[s.Key] = v
+		}
+	}
+	if err := apply(settings, env, "environment"); err != nil {
+		return err
+	}
+	return apply(settings, overrides, "overrides")
+}
+
+// EnvVar returns the environment variable that sets key: prefix followed
+// by the key in upper case with dots replaced by underscores, e.g.
+// SALES_USERS_URL for users.url with prefix SALES_.
+func EnvVar(prefix, key string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Problems collects the validation errors of a configuration, so every
+// problem is reported at once. The zero value is ready to use.
+type Problems struct {
+	errs []error
+}
+
+// Check records a problem, described by format and args, unless ok.
+func (p *Problems) Check(ok bool, format string, args ...any) {
+	if !ok {
+		p.errs = append(p.errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...))
+	}
+}
+
+// Err returns every recorded problem joined, or nil if there are none.
+func (p *Problems) Err() error {
+	return errors.Join(p.errs...)
+}
+
+// apply sets the given values, failing on unknown keys and on values that
+// do not parse. source names where the values come from, for errors.
+func apply(settings []Setting, values map[string]string, source string) error {
+	var errs []error
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		i := slices.IndexFunc(settings, func(s Setting) bool { return s.Key == k })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s: unknown key %q", ErrInvalid, source, k))
+			continue
+		}
+		if err := settings[i].Set(values[k]); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %s: %v", ErrInvalid, source, k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readFile reads a YAML or TOML file into a map of keys to values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%w: unsupported file format %q (yaml or toml)", ErrInvalid, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", doc, values)
+	return values, nil
+}
+
+// flatten turns nested sections into dotted keys.
+func flatten(prefix string, doc map[string]any, values map[string]string) {
+	for k, v := range doc {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key+".", v, values)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
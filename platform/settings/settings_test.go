@@ -0,0 +1,115 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Addr    string
+	Timeout time.Duration
+	Retries int
+}
+
+func (c *testConfig) settings() []Setting {
+	return []Setting{
+		{"addr", String(&c.Addr)},
+		{"client.timeout", Duration(&c.Timeout)},
+		{"client.retries", Int(&c.Retries)},
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "test.yaml", `
+addr: ":9000"
+client:
+  timeout: 1s
+  retries: 3
+`)
+	t.Setenv("TEST_CLIENT_TIMEOUT", "2s")
+	t.Setenv("TEST_ADDR", ":9001")
+
+	cfg := testConfig{Retries: 1}
+	require.NoError(t, Load(cfg.settings(), "TEST_", path, map[string]string{"addr": ":9002"}))
+
+	// the environment overrides the file, and overrides the environment
+	assert.Equal(t, testConfig{Addr: ":9002", Timeout: 2 * time.Second, Retries: 3}, cfg)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "test.toml", `
+addr = ":9000"
+
+[client]
+retries = 5
+`)
+	cfg := testConfig{Timeout: time.Second}
+	require.NoError(t, Load(cfg.settings(), "TEST_", path, nil))
+	assert.Equal(t, testConfig{Addr: ":9000", Timeout: time.Second, Retries: 5}, cfg)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Run("unknown key in the file", func(t *testing.T) {
+		var cfg testConfig
+		err := Load(cfg.settings(), "TEST_", writeFile(t, "test.yaml", "port: 8081\n"), nil)
+		require.ErrorIs(t, err, ErrInvalid)
+		assert.Contains(t, err.Error(), `file `)
+		assert.Contains(t, err.Error(), `unknown key "port"`)
+	})
+
+	t.Run("every malformed value is reported", func(t *testing.T) {
+		t.Setenv("TEST_CLIENT_TIMEOUT", "2 seconds")
+		var cfg testConfig
+		err := Load(cfg.settings(), "TEST_", "", map[string]string{"client.retries": "many"})
+		require.ErrorIs(t, err, ErrInvalid)
+		assert.Contains(t, err.Error(), `environment: client.timeout: invalid duration "2 seconds"`)
+		assert.NotContains(t, err.Error(), "client.retries")
+
+		t.Setenv("TEST_CLIENT_TIMEOUT", "2s")
+		err = Load(cfg.settings(), "TEST_", "", map[string]string{"client.retries": "many", "client.port": "1"})
+		require.ErrorIs(t, err, ErrInvalid)
+		assert.Contains(t, err.Error(), `overrides: client.retries: invalid integer "many"`)
+		assert.Contains(t, err.Error(), `overrides: unknown key "client.port"`)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var cfg testConfig
+		err := Load(cfg.settings(), "TEST_", writeFile(t, "test.json", "{}"), nil)
+		assert.ErrorIs(t, err, ErrInvalid)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var cfg testConfig
+		err := Load(cfg.settings(), "TEST_", filepath.Join(t.TempDir(), "nope.yaml"), nil)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestEnvVar(t *testing.T) {
+	assert.Equal(t, "SALES_USERS_URL", EnvVar("SALES_", "users.url"))
+	assert.Equal(t, "USERS_ADDR", EnvVar("USERS_", "addr"))
+}
+
+func TestProblems(t *testing.T) {
+	var p Problems
+	assert.NoError(t, p.Err())
+
+	p.Check(true, "never reported")
+	p.Check(false, "addr %q is not host:port", "8080")
+	p.Check(false, "mode must be set")
+	err := p.Err()
+	require.ErrorIs(t, err, ErrInvalid)
+	assert.Equal(t, "invalid configuration: addr \"8080\" is not host:port\ninvalid configuration: mode must be set", err.Error())
+}